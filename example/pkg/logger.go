@@ -4,11 +4,7 @@ import (
 	"github.com/bgp59/logrusx"
 )
 
-// Create the root logger:
+// Create the root logger. The caller's source file is logged relative to its
+// module root, which is detected automatically; AddCallerSrcPathPrefix may be
+// used to override it.
 var RootLogger = logrusx.NewCollectableLogger()
-
-// Use init to add the module's root path (2 dirs up from here) to the list of
-// prefixes to be stripped when logging the caller's source file.
-func init() {
-	RootLogger.AddCallerSrcPathPrefix(2)
-}
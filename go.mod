@@ -4,7 +4,8 @@ go 1.23.5
 
 require (
 	github.com/sirupsen/logrus v1.9.3
+	github.com/spf13/pflag v1.0.10
+	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8
 	gopkg.in/natefinch/lumberjack.v2 v2.2.1
+	gopkg.in/yaml.v3 v3.0.1
 )
-
-require golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
@@ -0,0 +1,255 @@
+// Asynchronous writer, it keeps the actual writes off the logging path.
+
+package logrusx_internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Overflow policies, i.e. what to do when the queue is full:
+const (
+	// Wait for room in the queue:
+	ASYNC_OVERFLOW_BLOCK = "block"
+	// Drop the record being written:
+	ASYNC_OVERFLOW_DROP_NEWEST = "drop_newest"
+	// Drop the oldest queued record to make room:
+	ASYNC_OVERFLOW_DROP_OLDEST = "drop_oldest"
+	// Drop the record being written if it is less severe than the drop level,
+	// otherwise wait for room:
+	ASYNC_OVERFLOW_DROP_BELOW_LEVEL = "drop_below_level"
+)
+
+var AsyncOverflowPolicies = []string{
+	ASYNC_OVERFLOW_BLOCK,
+	ASYNC_OVERFLOW_DROP_NEWEST,
+	ASYNC_OVERFLOW_DROP_OLDEST,
+	ASYNC_OVERFLOW_DROP_BELOW_LEVEL,
+}
+
+type asyncRecord struct {
+	level logrus.Level
+	buf   []byte
+}
+
+// The records are queued and written by a background goroutine, optionally
+// via a buffer flushed periodically. Records at fatal or panic level are
+// flushed before returning, since the program is about to exit. After Close,
+// the records are written synchronously.
+type AsyncWriter struct {
+	out           io.Writer
+	bufOut        *bufio.Writer
+	policy        string
+	dropLevel     logrus.Level
+	flushInterval time.Duration
+
+	queue      chan *asyncRecord
+	flushReqCh chan chan error
+	closeCh    chan struct{}
+	done       chan struct{}
+	// The error from the final drain and flush, set before done is closed:
+	closeErr error
+
+	// Held for reading while queueing and for writing while closing:
+	closeMu sync.RWMutex
+	closed  bool
+	// Serialize the synchronous writes, after close:
+	outMu sync.Mutex
+
+	dropped atomic.Uint64
+}
+
+// Create and start the writer. If bufSize > 0, the writes to out are buffered
+// and flushed every flushInterval, if > 0, upon Flush and Close. Buffering
+// should not be used for message oriented outputs such as syslog, which
+// expect one record per write.
+func NewAsyncWriter(
+	out io.Writer,
+	queueSize int,
+	policy string,
+	dropLevel logrus.Level,
+	bufSize int,
+	flushInterval time.Duration,
+) *AsyncWriter {
+	w := &AsyncWriter{
+		out:           out,
+		policy:        policy,
+		dropLevel:     dropLevel,
+		flushInterval: flushInterval,
+		queue:         make(chan *asyncRecord, queueSize),
+		flushReqCh:    make(chan chan error),
+		closeCh:       make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	if bufSize > 0 {
+		w.bufOut = bufio.NewWriterSize(out, bufSize)
+	}
+	go w.run()
+	return w
+}
+
+func (w *AsyncWriter) write(buf []byte) error {
+	var err error
+	if w.bufOut != nil {
+		_, err = w.bufOut.Write(buf)
+	} else {
+		_, err = w.out.Write(buf)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+	}
+	return err
+}
+
+func (w *AsyncWriter) flush() error {
+	if w.bufOut != nil {
+		return w.bufOut.Flush()
+	}
+	return nil
+}
+
+// Write all the records queued so far and flush; return the 1st error:
+func (w *AsyncWriter) drainAndFlush() error {
+	var firstErr error
+	for {
+		select {
+		case rec := <-w.queue:
+			if err := w.write(rec.buf); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		default:
+			if err := w.flush(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+			return firstErr
+		}
+	}
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+
+	var tickerC <-chan time.Time
+	if w.bufOut != nil && w.flushInterval > 0 {
+		ticker := time.NewTicker(w.flushInterval)
+		defer ticker.Stop()
+		tickerC = ticker.C
+	}
+
+	for {
+		select {
+		case rec := <-w.queue:
+			w.write(rec.buf)
+		case <-tickerC:
+			if err := w.flush(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to flush log, %v\n", err)
+			}
+		case replyCh := <-w.flushReqCh:
+			replyCh <- w.drainAndFlush()
+		case <-w.closeCh:
+			w.closeErr = w.drainAndFlush()
+			return
+		}
+	}
+}
+
+// Queue a copy of the record, according to the overflow policy:
+func (w *AsyncWriter) WriteLevel(level logrus.Level, buf []byte) error {
+	if len(buf) == 0 {
+		return nil
+	}
+
+	w.closeMu.RLock()
+	if w.closed {
+		w.closeMu.RUnlock()
+		w.outMu.Lock()
+		defer w.outMu.Unlock()
+		_, err := w.out.Write(buf)
+		return err
+	}
+
+	rec := &asyncRecord{level: level, buf: append([]byte(nil), buf...)}
+	policy := w.policy
+	if level <= logrus.FatalLevel {
+		// Never drop a record that precedes an exit or a panic:
+		policy = ASYNC_OVERFLOW_BLOCK
+	}
+	switch policy {
+	case ASYNC_OVERFLOW_DROP_NEWEST:
+		select {
+		case w.queue <- rec:
+		default:
+			w.dropped.Add(1)
+		}
+	case ASYNC_OVERFLOW_DROP_OLDEST:
+		for queued := false; !queued; {
+			select {
+			case w.queue <- rec:
+				queued = true
+			default:
+				select {
+				case <-w.queue:
+					w.dropped.Add(1)
+				default:
+				}
+			}
+		}
+	case ASYNC_OVERFLOW_DROP_BELOW_LEVEL:
+		if level > w.dropLevel {
+			select {
+			case w.queue <- rec:
+			default:
+				w.dropped.Add(1)
+			}
+		} else {
+			w.queue <- rec
+		}
+	default:
+		w.queue <- rec
+	}
+	w.closeMu.RUnlock()
+
+	if level <= logrus.FatalLevel {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Write all the records queued so far and flush the buffer, if any:
+func (w *AsyncWriter) Flush() error {
+	w.closeMu.RLock()
+	defer w.closeMu.RUnlock()
+	if w.closed {
+		return nil
+	}
+	replyCh := make(chan error, 1)
+	w.flushReqCh <- replyCh
+	return <-replyCh
+}
+
+// Write all the queued records, flush and stop the background goroutine. The
+// subsequent writes are synchronous. Return the 1st error from writing the
+// queued records or from flushing, i.e. whether records were lost.
+func (w *AsyncWriter) Close() error {
+	w.closeMu.Lock()
+	defer w.closeMu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	close(w.closeCh)
+	<-w.done
+	return w.closeErr
+}
+
+// The number of records dropped due to the queue being full:
+func (w *AsyncWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
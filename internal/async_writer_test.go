@@ -0,0 +1,194 @@
+package logrusx_internal
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// A writer blocking the 1st write until released, to fill up the queue:
+type blockingWriter struct {
+	m        sync.Mutex
+	buf      bytes.Buffer
+	started  chan struct{}
+	release  chan struct{}
+	nWritten int
+}
+
+func newBlockingWriter() *blockingWriter {
+	return &blockingWriter{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+}
+
+func (w *blockingWriter) Write(buf []byte) (int, error) {
+	w.m.Lock()
+	w.nWritten++
+	first := w.nWritten == 1
+	w.m.Unlock()
+	if first {
+		close(w.started)
+		<-w.release
+	}
+	w.m.Lock()
+	defer w.m.Unlock()
+	return w.buf.Write(buf)
+}
+
+func (w *blockingWriter) String() string {
+	w.m.Lock()
+	defer w.m.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncWriterOverflow(t *testing.T) {
+	for _, tc := range []struct {
+		policy      string
+		records     []asyncRecord
+		want        string
+		wantDropped uint64
+	}{
+		{
+			policy: ASYNC_OVERFLOW_DROP_NEWEST,
+			records: []asyncRecord{
+				{logrus.InfoLevel, []byte("2")},
+				{logrus.InfoLevel, []byte("3")},
+				{logrus.InfoLevel, []byte("4")},
+			},
+			want:        "123",
+			wantDropped: 1,
+		},
+		{
+			policy: ASYNC_OVERFLOW_DROP_OLDEST,
+			records: []asyncRecord{
+				{logrus.InfoLevel, []byte("2")},
+				{logrus.InfoLevel, []byte("3")},
+				{logrus.InfoLevel, []byte("4")},
+				{logrus.InfoLevel, []byte("5")},
+			},
+			want:        "145",
+			wantDropped: 2,
+		},
+		{
+			policy: ASYNC_OVERFLOW_DROP_BELOW_LEVEL,
+			records: []asyncRecord{
+				{logrus.InfoLevel, []byte("2")},
+				{logrus.InfoLevel, []byte("3")},
+				{logrus.DebugLevel, []byte("4")},
+				{logrus.InfoLevel, []byte("5")},
+			},
+			want:        "123",
+			wantDropped: 2,
+		},
+	} {
+		t.Run(tc.policy, func(t *testing.T) {
+			out := newBlockingWriter()
+			w := NewAsyncWriter(out, 2, tc.policy, logrus.WarnLevel, 0, 0)
+			w.WriteLevel(logrus.InfoLevel, []byte("1"))
+			<-out.started
+			for _, rec := range tc.records {
+				w.WriteLevel(rec.level, rec.buf)
+			}
+			close(out.release)
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != tc.want {
+				t.Errorf("out: want %q, got %q", tc.want, got)
+			}
+			if got := w.Dropped(); got != tc.wantDropped {
+				t.Errorf("dropped: want %d, got %d", tc.wantDropped, got)
+			}
+		})
+	}
+}
+
+func TestAsyncWriterBlock(t *testing.T) {
+	out := newBlockingWriter()
+	w := NewAsyncWriter(out, 1, ASYNC_OVERFLOW_BLOCK, logrus.WarnLevel, 0, 0)
+	w.WriteLevel(logrus.InfoLevel, []byte("1"))
+	<-out.started
+	w.WriteLevel(logrus.InfoLevel, []byte("2"))
+	done := make(chan struct{})
+	go func() {
+		w.WriteLevel(logrus.InfoLevel, []byte("3"))
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("write did not block on full queue")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(out.release)
+	<-done
+	w.Close()
+	if got, want := out.String(), "123"; got != want {
+		t.Errorf("out: want %q, got %q", want, got)
+	}
+}
+
+func TestAsyncWriterFlushAndClose(t *testing.T) {
+	out := &bytes.Buffer{}
+	outMu := &sync.Mutex{}
+	lockedOut := writerFunc(func(buf []byte) (int, error) {
+		outMu.Lock()
+		defer outMu.Unlock()
+		return out.Write(buf)
+	})
+	w := NewAsyncWriter(lockedOut, 16, ASYNC_OVERFLOW_BLOCK, logrus.WarnLevel, 1024, 0)
+	w.WriteLevel(logrus.InfoLevel, []byte("1"))
+	w.WriteLevel(logrus.InfoLevel, []byte("2"))
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	outMu.Lock()
+	if got, want := out.String(), "12"; got != want {
+		t.Errorf("out after flush: want %q, got %q", want, got)
+	}
+	outMu.Unlock()
+
+	// Fatal records are flushed right away:
+	w.WriteLevel(logrus.FatalLevel, []byte("3"))
+	outMu.Lock()
+	if got, want := out.String(), "123"; got != want {
+		t.Errorf("out after fatal: want %q, got %q", want, got)
+	}
+	outMu.Unlock()
+
+	w.WriteLevel(logrus.InfoLevel, []byte("4"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Synchronous after close:
+	w.WriteLevel(logrus.InfoLevel, []byte("5"))
+	if got, want := out.String(), "12345"; got != want {
+		t.Errorf("out after close: want %q, got %q", want, got)
+	}
+}
+
+func TestAsyncWriterCloseError(t *testing.T) {
+	wantErr := errors.New("disk full")
+	failingOut := writerFunc(func(buf []byte) (int, error) {
+		return 0, wantErr
+	})
+	// Buffered, the records are written to out by the final flush:
+	w := NewAsyncWriter(failingOut, 16, ASYNC_OVERFLOW_BLOCK, logrus.WarnLevel, 1024, 0)
+	w.WriteLevel(logrus.InfoLevel, []byte("1"))
+	if err := w.Close(); !errors.Is(err, wantErr) {
+		t.Errorf("want %v, got %v", wantErr, err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("2nd close: want nil, got %v", err)
+	}
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(buf []byte) (int, error) {
+	return f(buf)
+}
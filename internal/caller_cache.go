@@ -0,0 +1,100 @@
+// Caller cache, PC -> prettyfied function and file:line#, w/ a lock-free read
+// path and an optional size bound.
+
+package logrusx_internal
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+const CALLER_CACHE_MAX_SIZE_DEFAULT = 16384
+
+type callerCacheGen struct {
+	entries sync.Map // uintptr -> *LogFuncFilePair
+	size    atomic.Int64
+}
+
+// The entries are added to the current generation; when bounded, the current
+// generation becomes the previous one once it reaches half the max size and
+// the previous one is discarded. The entries found in the previous generation
+// are promoted to the current one, such that the callers in use survive the
+// rotation, i.e. the eviction approximates LRU at O(1) cost.
+type callerCacheGens struct {
+	cur, prev *callerCacheGen
+}
+
+type CallerCache struct {
+	gens    atomic.Pointer[callerCacheGens]
+	maxSize atomic.Int64
+}
+
+// Create a cache w/ the given max size, use 0 for unbounded. The number of
+// entries is kept within the max size, rounded up to an even number.
+func NewCallerCache(maxSize int) *CallerCache {
+	c := &CallerCache{}
+	c.SetMaxSize(maxSize)
+	return c
+}
+
+// Return the cached entry, nil if not found:
+func (c *CallerCache) Load(pc uintptr) *LogFuncFilePair {
+	gens := c.gens.Load()
+	if funcFile, ok := gens.cur.entries.Load(pc); ok {
+		return funcFile.(*LogFuncFilePair)
+	}
+	if gens.prev == nil {
+		return nil
+	}
+	funcFile, ok := gens.prev.entries.Load(pc)
+	if !ok {
+		return nil
+	}
+	c.store(gens, pc, funcFile.(*LogFuncFilePair))
+	return funcFile.(*LogFuncFilePair)
+}
+
+func (c *CallerCache) Store(pc uintptr, funcFile *LogFuncFilePair) {
+	c.store(c.gens.Load(), pc, funcFile)
+}
+
+// Store into a given generation set, such that the entries promoted from a
+// set replaced in the meantime, e.g. by Reset, are discarded along w/ it:
+func (c *CallerCache) store(gens *callerCacheGens, pc uintptr, funcFile *LogFuncFilePair) {
+	if _, loaded := gens.cur.entries.LoadOrStore(pc, funcFile); loaded {
+		return
+	}
+	size := gens.cur.size.Add(1)
+	if maxSize := c.maxSize.Load(); maxSize > 0 && size >= (maxSize+1)/2 {
+		// Only one of the concurrent stores reaching the limit rotates:
+		c.gens.CompareAndSwap(gens, &callerCacheGens{cur: &callerCacheGen{}, prev: gens.cur})
+	}
+}
+
+// Discard all the entries:
+func (c *CallerCache) Reset() {
+	c.gens.Store(&callerCacheGens{cur: &callerCacheGen{}})
+}
+
+// Set the max size, use 0 for unbounded; the cache is reset.
+func (c *CallerCache) SetMaxSize(maxSize int) {
+	if maxSize < 0 {
+		maxSize = 0
+	}
+	c.maxSize.Store(int64(maxSize))
+	c.Reset()
+}
+
+func (c *CallerCache) MaxSize() int {
+	return int(c.maxSize.Load())
+}
+
+// Return the number of entries, approximate under concurrent stores:
+func (c *CallerCache) Len() int {
+	gens := c.gens.Load()
+	size := gens.cur.size.Load()
+	if gens.prev != nil {
+		size += gens.prev.size.Load()
+	}
+	return int(size)
+}
@@ -0,0 +1,150 @@
+package logrusx_internal
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCallerCacheBounded(t *testing.T) {
+	c := NewCallerCache(4)
+	for pc := uintptr(1); pc <= 100; pc++ {
+		c.Store(pc, &LogFuncFilePair{file: fmt.Sprintf("file.go:%d", pc)})
+		// Keep pc 1 in use:
+		if c.Load(1) == nil {
+			t.Fatalf("pc 1 evicted after storing pc %d", pc)
+		}
+		if n := c.Len(); n > 4 {
+			t.Fatalf("pc %d: want len <= 4, got %d", pc, n)
+		}
+	}
+	if funcFile := c.Load(100); funcFile == nil || funcFile.file != "file.go:100" {
+		t.Errorf("pc 100: want %q, got %v", "file.go:100", funcFile)
+	}
+	if funcFile := c.Load(50); funcFile != nil {
+		t.Errorf("pc 50: want nil, got %v", funcFile)
+	}
+	c.Reset()
+	if n := c.Len(); n != 0 {
+		t.Errorf("reset: want len 0, got %d", n)
+	}
+}
+
+func TestCallerCacheUnbounded(t *testing.T) {
+	c := NewCallerCache(0)
+	for pc := uintptr(1); pc <= 100; pc++ {
+		c.Store(pc, &LogFuncFilePair{})
+	}
+	if n := c.Len(); n != 100 {
+		t.Errorf("want len 100, got %d", n)
+	}
+	for pc := uintptr(1); pc <= 100; pc++ {
+		if c.Load(pc) == nil {
+			t.Fatalf("pc %d: want entry, got nil", pc)
+		}
+	}
+}
+
+func TestCallerPrettyfierConcurrent(t *testing.T) {
+	prettyfier := NewCallerPrettyfier()
+	prettyfier.SetCacheMaxSize(8)
+	frames := newBenchFrames(32)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				f := frames[j%len(frames)]
+				if j%100 == 0 {
+					prettyfier.SetFuncNameMode([]string{FUNC_NAME_SHORT, FUNC_NAME_OFF}[j/100%2])
+				}
+				if _, file := prettyfier.Pretiffy(f); file != fmt.Sprintf("pkg/file.go:%d", f.Line) {
+					t.Errorf("want %q, got %q", fmt.Sprintf("pkg/file.go:%d", f.Line), file)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// The prettyfier before the lock-free cache, kept as the benchmark baseline:
+type mutexCallerPrettyfier struct {
+	m                  *sync.Mutex
+	funcFileCache      map[uintptr]*LogFuncFilePair
+	moduleDirPathCache *ModuleDirPathCache
+}
+
+func (p *mutexCallerPrettyfier) Pretiffy(f *runtime.Frame) (function string, file string) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	funcFile := p.funcFileCache[f.PC]
+	if funcFile == nil {
+		funcFile = &LogFuncFilePair{
+			formatFuncName(f.Function, FUNC_NAME_OFF),
+			fmt.Sprintf("%s:%d", p.moduleDirPathCache.stripPrefix(f.File, f.Function), f.Line),
+		}
+		p.funcFileCache[f.PC] = funcFile
+	}
+	return funcFile.function, funcFile.file
+}
+
+func newBenchFrames(n int) []*runtime.Frame {
+	frames := make([]*runtime.Frame, n)
+	for i := range frames {
+		frames[i] = &runtime.Frame{
+			PC:       uintptr(i + 1),
+			File:     "/src/module/pkg/file.go",
+			Line:     i + 1,
+			Function: "example.com/module/pkg.Func",
+		}
+	}
+	return frames
+}
+
+// Run w/ -cpu, e.g. -cpu 1,4,16, for GOMAXPROCS-wide parallel logging; the
+// number of frames is the number of call sites.
+func benchmarkPrettyfier(b *testing.B, pretiffy func(*runtime.Frame) (string, string), numFrames int) {
+	frames := newBenchFrames(numFrames)
+	// Measure the steady state, i.e. w/ the cache warmed up:
+	for i := 0; i < 2; i++ {
+		for _, f := range frames {
+			pretiffy(f)
+		}
+	}
+	next := atomic.Uint64{}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(next.Add(1)) * 7919
+		for pb.Next() {
+			pretiffy(frames[i%numFrames])
+			i++
+		}
+	})
+}
+
+func BenchmarkCallerPrettyfier(b *testing.B) {
+	for _, numFrames := range []int{16, 4096} {
+		b.Run(fmt.Sprintf("mutex/frames=%d", numFrames), func(b *testing.B) {
+			moduleDirPathCache := NewModuleDirPathCache()
+			moduleDirPathCache.addPrefix("/src/module/")
+			p := &mutexCallerPrettyfier{
+				m:                  &sync.Mutex{},
+				funcFileCache:      make(map[uintptr]*LogFuncFilePair),
+				moduleDirPathCache: moduleDirPathCache,
+			}
+			benchmarkPrettyfier(b, p.Pretiffy, numFrames)
+		})
+		for _, maxSize := range []int{0, CALLER_CACHE_MAX_SIZE_DEFAULT, 1024} {
+			b.Run(fmt.Sprintf("lock-free/frames=%d/max=%d", numFrames, maxSize), func(b *testing.B) {
+				p := NewCallerPrettyfier()
+				p.moduleDirPathCache.addPrefix("/src/module/")
+				p.SetCacheMaxSize(maxSize)
+				benchmarkPrettyfier(b, p.Pretiffy, numFrames)
+			})
+		}
+	}
+}
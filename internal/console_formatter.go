@@ -0,0 +1,210 @@
+// Console formatter, for interactive terminals.
+
+package logrusx_internal
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	CONSOLE_TIMESTAMP_FORMAT = "15:04:05.000"
+	// The message is padded to this width if followed by fields, for alignment:
+	CONSOLE_MSG_MIN_WIDTH = 40
+
+	ANSI_RESET   = "\x1b[0m"
+	ANSI_RED     = "\x1b[31m"
+	ANSI_GREEN   = "\x1b[32m"
+	ANSI_YELLOW  = "\x1b[33m"
+	ANSI_BLUE    = "\x1b[34m"
+	ANSI_MAGENTA = "\x1b[35m"
+	ANSI_CYAN    = "\x1b[36m"
+	ANSI_GRAY    = "\x1b[90m"
+	ANSI_BOLD    = "\x1b[1m"
+)
+
+var consoleLevelColors = map[logrus.Level]string{
+	logrus.PanicLevel: ANSI_BOLD + ANSI_RED,
+	logrus.FatalLevel: ANSI_BOLD + ANSI_RED,
+	logrus.ErrorLevel: ANSI_RED,
+	logrus.WarnLevel:  ANSI_YELLOW,
+	logrus.InfoLevel:  ANSI_GREEN,
+	logrus.DebugLevel: ANSI_BLUE,
+	logrus.TraceLevel: ANSI_GRAY,
+}
+
+// The component color is based on the name hash, such that it is stable
+// across runs:
+var consoleCompColors = []string{
+	ANSI_CYAN,
+	ANSI_MAGENTA,
+	ANSI_BLUE,
+	ANSI_YELLOW,
+	ANSI_GREEN,
+	ANSI_BOLD + ANSI_CYAN,
+	ANSI_BOLD + ANSI_MAGENTA,
+	ANSI_BOLD + ANSI_BLUE,
+}
+
+var consoleLevelNames = map[logrus.Level]string{
+	logrus.PanicLevel: "PANIC",
+	logrus.FatalLevel: "FATAL",
+	logrus.ErrorLevel: "ERROR",
+	logrus.WarnLevel:  "WARN",
+	logrus.InfoLevel:  "INFO",
+	logrus.DebugLevel: "DEBUG",
+	logrus.TraceLevel: "TRACE",
+}
+
+// Whether to use colors for an output: NO_COLOR, if not empty, disables them,
+// FORCE_COLOR, if not empty, 0 or false, enables them, otherwise they are
+// used only for terminals. See https://no-color.org/ and
+// https://force-color.org/.
+func ShouldColorize(out io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	switch strings.ToLower(os.Getenv("FORCE_COLOR")) {
+	case "", "0", "false":
+	default:
+		return true
+	}
+	return IsTerminal(out)
+}
+
+// Format the entry for human consumption, as:
+//
+//	TIME LEVEL [COMP] MSG FILE KEY=VAL...
+//
+// w/ the time shortened, the columns aligned and, when enabled, the level and
+// the component colored.
+type ConsoleFormatter struct {
+	TimestampFormat  string
+	CallerPrettyfier func(*runtime.Frame) (function string, file string)
+	// For the fields following the message:
+	KeyOrder *LogFieldKeyOrder
+
+	colors atomic.Bool
+	// The component column width, it grows w/ the longest name seen so far:
+	m         sync.Mutex
+	compWidth int
+}
+
+func NewConsoleFormatter(
+	callerPrettyfier func(*runtime.Frame) (string, string),
+	keyOrder *LogFieldKeyOrder,
+) *ConsoleFormatter {
+	if keyOrder == nil {
+		keyOrder = defaultLogFieldKeyOrder
+	}
+	return &ConsoleFormatter{
+		TimestampFormat:  CONSOLE_TIMESTAMP_FORMAT,
+		CallerPrettyfier: callerPrettyfier,
+		KeyOrder:         keyOrder,
+	}
+}
+
+// Enable/disable colors based on the output, see ShouldColorize:
+func (f *ConsoleFormatter) SetOutput(out io.Writer) {
+	f.colors.Store(ShouldColorize(out))
+}
+
+func (f *ConsoleFormatter) SetColors(colors bool) {
+	f.colors.Store(colors)
+}
+
+func (f *ConsoleFormatter) updateCompWidth(comp string) int {
+	f.m.Lock()
+	defer f.m.Unlock()
+	if len(comp) > f.compWidth {
+		f.compWidth = len(comp)
+	}
+	return f.compWidth
+}
+
+func (f *ConsoleFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	b := entry.Buffer
+	if b == nil {
+		b = &bytes.Buffer{}
+	}
+	colors := f.colors.Load()
+	colorize := func(color, s string) {
+		if colors {
+			b.WriteString(color)
+			b.WriteString(s)
+			b.WriteString(ANSI_RESET)
+		} else {
+			b.WriteString(s)
+		}
+	}
+	levelColor := consoleLevelColors[entry.Level]
+
+	colorize(ANSI_GRAY, entry.Time.Format(f.TimestampFormat))
+	b.WriteByte(' ')
+	colorize(levelColor, fmt.Sprintf("%-5s", consoleLevelNames[entry.Level]))
+
+	keys := make([]string, 0, len(entry.Data))
+	comp := ""
+	for key, val := range entry.Data {
+		if key == LOGGER_COMPONENT_FIELD_NAME {
+			comp = fmt.Sprint(val)
+		} else {
+			keys = append(keys, key)
+		}
+	}
+	if compWidth := f.updateCompWidth(comp); compWidth > 0 {
+		b.WriteByte(' ')
+		if comp != "" {
+			h := fnv.New32a()
+			h.Write([]byte(comp))
+			colorize(consoleCompColors[h.Sum32()%uint32(len(consoleCompColors))], "["+comp+"]")
+		} else {
+			b.WriteString("  ")
+		}
+		b.WriteString(strings.Repeat(" ", compWidth-len(comp)))
+	}
+
+	file := ""
+	if entry.HasCaller() {
+		file = fmt.Sprintf("%s:%d", entry.Caller.File, entry.Caller.Line)
+		if f.CallerPrettyfier != nil {
+			_, file = f.CallerPrettyfier(entry.Caller)
+		}
+	}
+
+	b.WriteByte(' ')
+	msg := strings.TrimSuffix(entry.Message, "\n")
+	b.WriteString(msg)
+	if file != "" || len(keys) > 0 {
+		if pad := CONSOLE_MSG_MIN_WIDTH - len(msg); pad > 0 {
+			b.WriteString(strings.Repeat(" ", pad))
+		}
+	}
+	if file != "" {
+		b.WriteByte(' ')
+		colorize(ANSI_GRAY, file)
+	}
+
+	f.KeyOrder.Sort(keys)
+	for _, key := range keys {
+		val := entry.Data[key]
+		if err, ok := val.(error); ok {
+			val = err.Error()
+		}
+		b.WriteByte(' ')
+		colorize(levelColor, key)
+		b.WriteByte('=')
+		writeLogfmtValue(b, fmt.Sprint(val))
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
@@ -0,0 +1,89 @@
+package logrusx_internal
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestShouldColorize(t *testing.T) {
+	for _, tc := range []struct {
+		noColor, forceColor string
+		want                bool
+	}{
+		{"", "", false},
+		{"", "1", true},
+		{"", "0", false},
+		{"", "false", false},
+		{"1", "1", false},
+	} {
+		t.Setenv("NO_COLOR", tc.noColor)
+		t.Setenv("FORCE_COLOR", tc.forceColor)
+		// A buffer is never a terminal:
+		if got := ShouldColorize(&bytes.Buffer{}); got != tc.want {
+			t.Errorf("NO_COLOR=%q FORCE_COLOR=%q: want %v, got %v", tc.noColor, tc.forceColor, tc.want, got)
+		}
+	}
+}
+
+func TestConsoleFormatter(t *testing.T) {
+	entryTime := time.Date(2024, 1, 2, 3, 4, 5, 678000000, time.UTC)
+	prettyfier := NewCallerPrettyfier()
+	frame := &runtime.Frame{File: "/src/module/pkg/file.go", Line: 13, PC: 1}
+
+	format := func(f *ConsoleFormatter, level logrus.Level, msg string, data logrus.Fields) string {
+		buf, err := f.Format(&logrus.Entry{
+			Time:    entryTime,
+			Level:   level,
+			Message: msg,
+			Data:    data,
+			Caller:  frame,
+			Logger:  &logrus.Logger{ReportCaller: true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(buf)
+	}
+
+	f := NewConsoleFormatter(prettyfier.Pretiffy, nil)
+	for _, tc := range []struct {
+		level logrus.Level
+		msg   string
+		data  logrus.Fields
+		want  string
+	}{
+		{
+			logrus.InfoLevel, "no comp", logrus.Fields{"k": "v w/ space"},
+			"03:04:05.678 INFO  no comp" + strings.Repeat(" ", 33) + ` pkg/file.go:13 k="v w/ space"` + "\n",
+		},
+		{
+			logrus.WarnLevel, "comp", logrus.Fields{LOGGER_COMPONENT_FIELD_NAME: "db"},
+			"03:04:05.678 WARN  [db] comp" + strings.Repeat(" ", 36) + " pkg/file.go:13\n",
+		},
+		{
+			logrus.ErrorLevel, "longer comp", logrus.Fields{LOGGER_COMPONENT_FIELD_NAME: "http"},
+			"03:04:05.678 ERROR [http] longer comp" + strings.Repeat(" ", 29) + " pkg/file.go:13\n",
+		},
+		{
+			logrus.DebugLevel, "aligned", logrus.Fields{LOGGER_COMPONENT_FIELD_NAME: "db"},
+			"03:04:05.678 DEBUG [db]   aligned" + strings.Repeat(" ", 33) + " pkg/file.go:13\n",
+		},
+	} {
+		if got := format(f, tc.level, tc.msg, tc.data); got != tc.want {
+			t.Errorf("\nwant: %q\n got: %q", tc.want, got)
+		}
+	}
+
+	f.SetColors(true)
+	got := format(f, logrus.WarnLevel, "colored", logrus.Fields{LOGGER_COMPONENT_FIELD_NAME: "db", "k": 1})
+	for _, want := range []string{ANSI_YELLOW + "WARN " + ANSI_RESET, "[db]" + ANSI_RESET, ANSI_YELLOW + "k" + ANSI_RESET + "=1"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%q: missing %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Native systemd-journald protocol support, see
+// https://systemd.io/JOURNAL_NATIVE_PROTOCOL/
+
+package logrusx_internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	JOURNALD_SOCKET_PATH = "/run/systemd/journal/socket"
+
+	JOURNALD_FIELD_MESSAGE           = "MESSAGE"
+	JOURNALD_FIELD_PRIORITY          = "PRIORITY"
+	JOURNALD_FIELD_SYSLOG_IDENTIFIER = "SYSLOG_IDENTIFIER"
+	// Prefix for the logrus fields that would otherwise clash w/ the above:
+	JOURNALD_FIELD_CLASH_PREFIX = "FIELD_"
+
+	JOURNALD_FIELD_NAME_MAX_LEN = 64
+)
+
+var journaldReservedFields = map[string]bool{
+	JOURNALD_FIELD_MESSAGE:           true,
+	JOURNALD_FIELD_PRIORITY:          true,
+	JOURNALD_FIELD_SYSLOG_IDENTIFIER: true,
+}
+
+// Format the entry as a journal entry, each logrus field as a journal field.
+// The field names are converted to the journal convention, i.e. upper case
+// letters, digits and underscores, not starting w/ an underscore, which is
+// reserved for the trusted fields added by journald.
+type JournaldFormatter struct {
+	// SYSLOG_IDENTIFIER:
+	AppName string
+	// Used for the caller info, if enabled:
+	CallerPrettyfier *CallerPrettyfier
+	KeyOrder         *LogFieldKeyOrder
+}
+
+func NewJournaldFormatter(appName string, pretyffier *CallerPrettyfier, keyOrder *LogFieldKeyOrder) *JournaldFormatter {
+	if keyOrder == nil {
+		keyOrder = defaultLogFieldKeyOrder
+	}
+	return &JournaldFormatter{
+		AppName:          appName,
+		CallerPrettyfier: pretyffier,
+		KeyOrder:         keyOrder,
+	}
+}
+
+func (f *JournaldFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	b := entry.Buffer
+	if b == nil {
+		b = &bytes.Buffer{}
+	}
+
+	writeJournaldField(b, JOURNALD_FIELD_MESSAGE, entry.Message)
+	writeJournaldField(b, JOURNALD_FIELD_PRIORITY, strconv.Itoa(SyslogSeverity(entry.Level)))
+	if f.AppName != "" {
+		writeJournaldField(b, JOURNALD_FIELD_SYSLOG_IDENTIFIER, f.AppName)
+	}
+	if entry.HasCaller() {
+		function, file := entry.Caller.Function, fmt.Sprintf("%s:%d", entry.Caller.File, entry.Caller.Line)
+		if f.CallerPrettyfier != nil {
+			function, file = f.CallerPrettyfier.Pretiffy(entry.Caller)
+		}
+		if file != "" {
+			writeJournaldField(b, JournaldFieldName(logrus.FieldKeyFile), file)
+		}
+		if function != "" {
+			writeJournaldField(b, JournaldFieldName(logrus.FieldKeyFunc), function)
+		}
+	}
+
+	keys := make([]string, 0, len(entry.Data))
+	for key := range entry.Data {
+		keys = append(keys, key)
+	}
+	f.KeyOrder.Sort(keys)
+	for _, key := range keys {
+		val := entry.Data[key]
+		if err, ok := val.(error); ok {
+			val = err.Error()
+		}
+		writeJournaldField(b, JournaldFieldName(key), fmt.Sprint(val))
+	}
+	return b.Bytes(), nil
+}
+
+// Convert a logrus field name into a journal field name:
+func JournaldFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+			return r
+		case 'a' <= r && r <= 'z':
+			return r - 'a' + 'A'
+		}
+		return '_'
+	}, key)
+	name = strings.TrimLeft(name, "_")
+	if name == "" || journaldReservedFields[name] || ('0' <= name[0] && name[0] <= '9') {
+		name = JOURNALD_FIELD_CLASH_PREFIX + name
+	}
+	if len(name) > JOURNALD_FIELD_NAME_MAX_LEN {
+		name = name[:JOURNALD_FIELD_NAME_MAX_LEN]
+	}
+	return name
+}
+
+// Values w/ newlines use the binary safe encoding: name, newline, little
+// endian 64 bit length, value, newline.
+func writeJournaldField(b *bytes.Buffer, name, val string) {
+	b.WriteString(name)
+	if strings.ContainsRune(val, '\n') {
+		b.WriteByte('\n')
+		binary.Write(b, binary.LittleEndian, uint64(len(val)))
+	} else {
+		b.WriteByte('=')
+	}
+	b.WriteString(val)
+	b.WriteByte('\n')
+}
+
+// Send each write, expected to be a complete journal entry, as a datagram to
+// journald. Entries too large for a datagram are passed via a file
+// descriptor, where supported.
+type JournaldWriter struct {
+	// The journald socket, if empty JOURNALD_SOCKET_PATH:
+	SocketPath string
+
+	m    sync.Mutex
+	conn *net.UnixConn
+	addr *net.UnixAddr
+}
+
+func (w *JournaldWriter) connect() error {
+	socketPath := w.SocketPath
+	if socketPath == "" {
+		socketPath = JOURNALD_SOCKET_PATH
+	}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	w.conn, w.addr = conn, &net.UnixAddr{Name: socketPath, Net: "unixgram"}
+	return nil
+}
+
+func (w *JournaldWriter) Write(buf []byte) (int, error) {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return 0, err
+		}
+	}
+	_, _, err := w.conn.WriteMsgUnix(buf, nil, w.addr)
+	if err != nil && isJournaldTooLarge(err) {
+		err = journaldSendViaFile(w.conn, w.addr, buf)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(buf), nil
+}
+
+func (w *JournaldWriter) Close() error {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package logrusx_internal
+
+import (
+	"fmt"
+	"net"
+)
+
+func isJournaldTooLarge(err error) bool {
+	return false
+}
+
+func journaldSendViaFile(conn *net.UnixConn, addr *net.UnixAddr, buf []byte) error {
+	return fmt.Errorf("journal entry too large: %d bytes", len(buf))
+}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package logrusx_internal
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+// Whether the write failed because the entry is too large for a datagram:
+func isJournaldTooLarge(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)
+}
+
+// Pass the entry via a file descriptor, to an unlinked temporary file, the way
+// the native protocol handles entries too large for a datagram:
+func journaldSendViaFile(conn *net.UnixConn, addr *net.UnixAddr, buf []byte) error {
+	file, err := os.CreateTemp("/dev/shm", "logrusx-journal-")
+	if err != nil {
+		file, err = os.CreateTemp("", "logrusx-journal-")
+		if err != nil {
+			return err
+		}
+	}
+	defer file.Close()
+	if err := os.Remove(file.Name()); err != nil {
+		return err
+	}
+	if _, err := file.Write(buf); err != nil {
+		return err
+	}
+	_, _, err = conn.WriteMsgUnix(nil, syscall.UnixRights(int(file.Fd())), addr)
+	return err
+}
@@ -0,0 +1,73 @@
+//go:build unix
+
+package logrusx_internal
+
+import (
+	"io"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestJournaldFieldName(t *testing.T) {
+	for _, tc := range []struct {
+		key, want string
+	}{
+		{"comp", "COMP"},
+		{"req-id", "REQ_ID"},
+		{"_private", "PRIVATE"},
+		{"message", "FIELD_MESSAGE"},
+		{"1st", "FIELD_1ST"},
+		{strings.Repeat("k", 70), strings.Repeat("K", JOURNALD_FIELD_NAME_MAX_LEN)},
+	} {
+		if got := JournaldFieldName(tc.key); got != tc.want {
+			t.Errorf("JournaldFieldName(%q): want %q, got %q", tc.key, tc.want, got)
+		}
+	}
+}
+
+func TestJournaldWriterLargeEntry(t *testing.T) {
+	sockPath := path.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sockPath, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	writer := &JournaldWriter{SocketPath: sockPath}
+	defer writer.Close()
+	// Larger than the max datagram size:
+	entry := []byte("MESSAGE=" + strings.Repeat("x", 4*1024*1024) + "\n")
+	if _, err := writer.Write(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	oob := make([]byte, syscall.CmsgSpace(4))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, oobn, _, _, err := conn.ReadMsgUnix(nil, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("want 1 control message, got %d (err: %v)", len(msgs), err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("want 1 fd, got %d (err: %v)", len(fds), err)
+	}
+	file := os.NewFile(uintptr(fds[0]), "journal-entry")
+	defer file.Close()
+	file.Seek(0, io.SeekStart)
+	got, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(entry) {
+		t.Errorf("want %d bytes entry, got %d bytes", len(entry), len(got))
+	}
+}
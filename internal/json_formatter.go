@@ -0,0 +1,310 @@
+// JSON formatter w/ configurable field keys, for log backends expecting
+// specific names, e.g. Elastic Common Schema, Google Cloud Logging or Datadog.
+
+package logrusx_internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const JSON_CLASH_PREFIX = "fields."
+
+// Presets:
+const (
+	JSON_FIELD_PRESET_ECS     = "ecs"
+	JSON_FIELD_PRESET_GCP     = "gcp"
+	JSON_FIELD_PRESET_DATADOG = "datadog"
+)
+
+// The names used for overriding the keys, in JSON field maps:
+const (
+	JSON_FIELD_TIME            = logrus.FieldKeyTime
+	JSON_FIELD_LEVEL           = logrus.FieldKeyLevel
+	JSON_FIELD_MSG             = logrus.FieldKeyMsg
+	JSON_FIELD_FILE            = logrus.FieldKeyFile
+	JSON_FIELD_LINE            = "line"
+	JSON_FIELD_FUNC            = logrus.FieldKeyFunc
+	JSON_FIELD_COMP            = LOGGER_COMPONENT_FIELD_NAME
+	JSON_FIELD_SOURCE_LOCATION = "source_location"
+)
+
+// The keys used for the fixed fields:
+type JsonFieldKeys struct {
+	Time, Level, Msg, File, Func, Comp string
+	// If not empty, the line# is split from the file and logged separately,
+	// as a number, under this key:
+	Line string
+	// If not empty, the caller info is logged as an object under this key,
+	// w/ file, line and function members, in which case File, Line and Func
+	// are ignored:
+	SourceLocation string
+	// The level names, w/ the logrus names used for the missing levels:
+	LevelNames map[logrus.Level]string
+}
+
+func defaultJsonFieldKeys() *JsonFieldKeys {
+	return &JsonFieldKeys{
+		Time:  logrus.FieldKeyTime,
+		Level: logrus.FieldKeyLevel,
+		Msg:   logrus.FieldKeyMsg,
+		File:  logrus.FieldKeyFile,
+		Func:  logrus.FieldKeyFunc,
+		Comp:  LOGGER_COMPONENT_FIELD_NAME,
+	}
+}
+
+var jsonFieldPresets = map[string]func() *JsonFieldKeys{
+	// See https://www.elastic.co/guide/en/ecs/current/ecs-log.html:
+	JSON_FIELD_PRESET_ECS: func() *JsonFieldKeys {
+		return &JsonFieldKeys{
+			Time:  "@timestamp",
+			Level: "log.level",
+			Msg:   "message",
+			File:  "log.origin.file.name",
+			Line:  "log.origin.file.line",
+			Func:  "log.origin.function",
+			Comp:  "log.logger",
+		}
+	},
+	// See https://cloud.google.com/logging/docs/structured-logging:
+	JSON_FIELD_PRESET_GCP: func() *JsonFieldKeys {
+		return &JsonFieldKeys{
+			Time:           "time",
+			Level:          "severity",
+			Msg:            "message",
+			SourceLocation: "logging.googleapis.com/sourceLocation",
+			Comp:           "component",
+			LevelNames: map[logrus.Level]string{
+				logrus.PanicLevel: "ALERT",
+				logrus.FatalLevel: "CRITICAL",
+				logrus.ErrorLevel: "ERROR",
+				logrus.WarnLevel:  "WARNING",
+				logrus.InfoLevel:  "INFO",
+				logrus.DebugLevel: "DEBUG",
+				logrus.TraceLevel: "DEBUG",
+			},
+		}
+	},
+	// See https://docs.datadoghq.com/logs/log_configuration/attributes_naming_convention/:
+	JSON_FIELD_PRESET_DATADOG: func() *JsonFieldKeys {
+		return &JsonFieldKeys{
+			Time:  "timestamp",
+			Level: "status",
+			Msg:   "message",
+			File:  logrus.FieldKeyFile,
+			Func:  "logger.method_name",
+			Comp:  "logger.name",
+			LevelNames: map[logrus.Level]string{
+				logrus.PanicLevel: "emerg",
+				logrus.FatalLevel: "critical",
+				logrus.ErrorLevel: "error",
+				logrus.WarnLevel:  "warn",
+				logrus.InfoLevel:  "info",
+				logrus.DebugLevel: "debug",
+				logrus.TraceLevel: "trace",
+			},
+		}
+	},
+}
+
+func GetJsonFieldPresetNames() []string {
+	names := make([]string, 0, len(jsonFieldPresets))
+	for name := range jsonFieldPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var jsonFieldNames = []string{
+	JSON_FIELD_TIME,
+	JSON_FIELD_LEVEL,
+	JSON_FIELD_MSG,
+	JSON_FIELD_FILE,
+	JSON_FIELD_LINE,
+	JSON_FIELD_FUNC,
+	JSON_FIELD_COMP,
+	JSON_FIELD_SOURCE_LOCATION,
+}
+
+// Build the keys from a preset, "" standing for the logrus keys, and a field
+// map overriding them, indexed by JSON_FIELD_... names. An empty value in the
+// field map restores the logrus key, or it disables the line and the source
+// location. Return nil if both are empty, i.e. if the logrus keys are used.
+func NewJsonFieldKeys(preset string, fieldMap map[string]string) (*JsonFieldKeys, error) {
+	if preset == "" && len(fieldMap) == 0 {
+		return nil, nil
+	}
+	keys := defaultJsonFieldKeys()
+	if preset != "" {
+		newPresetKeys := jsonFieldPresets[preset]
+		if newPresetKeys == nil {
+			return nil, fmt.Errorf("invalid JSON field preset %q, must be one of %v", preset, GetJsonFieldPresetNames())
+		}
+		keys = newPresetKeys()
+	}
+	defaultKeys := defaultJsonFieldKeys()
+	for name, key := range fieldMap {
+		switch name {
+		case JSON_FIELD_TIME:
+			keys.Time = jsonKeyOrDefault(key, defaultKeys.Time)
+		case JSON_FIELD_LEVEL:
+			keys.Level = jsonKeyOrDefault(key, defaultKeys.Level)
+		case JSON_FIELD_MSG:
+			keys.Msg = jsonKeyOrDefault(key, defaultKeys.Msg)
+		case JSON_FIELD_FILE:
+			keys.File = jsonKeyOrDefault(key, defaultKeys.File)
+		case JSON_FIELD_LINE:
+			keys.Line = key
+		case JSON_FIELD_FUNC:
+			keys.Func = jsonKeyOrDefault(key, defaultKeys.Func)
+		case JSON_FIELD_COMP:
+			keys.Comp = jsonKeyOrDefault(key, defaultKeys.Comp)
+		case JSON_FIELD_SOURCE_LOCATION:
+			keys.SourceLocation = key
+		default:
+			return nil, fmt.Errorf("invalid JSON field %q, must be one of %v", name, jsonFieldNames)
+		}
+	}
+	return keys, nil
+}
+
+func jsonKeyOrDefault(key, defaultKey string) string {
+	if key != "" {
+		return key
+	}
+	return defaultKey
+}
+
+// Format the entry as JSON, like logrus.JSONFormatter, but w/ the keys and the
+// level names based on JsonFieldKeys. The fields clashing w/ the fixed keys
+// are prefixed w/ "fields.", as per logrus convention.
+type JsonFormatter struct {
+	CallerPrettyfier func(*runtime.Frame) (function string, file string)
+	// Return nil to disable the timestamp:
+	Timestamp func(time.Time) any
+	Keys      *JsonFieldKeys
+}
+
+func newJsonFormatter(
+	callerPrettyfier func(*runtime.Frame) (string, string),
+	timestamp func(time.Time) any,
+	keys *JsonFieldKeys,
+) *JsonFormatter {
+	if timestamp == nil {
+		timestamp = DefaultTimestampFormat
+	}
+	return &JsonFormatter{
+		CallerPrettyfier: callerPrettyfier,
+		Timestamp:        timestamp,
+		Keys:             keys,
+	}
+}
+
+func (f *JsonFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	keys := f.Keys
+	fixedKeys := map[string]bool{
+		keys.Time:  true,
+		keys.Level: true,
+		keys.Msg:   true,
+	}
+	if keys.SourceLocation != "" {
+		fixedKeys[keys.SourceLocation] = true
+	} else {
+		fixedKeys[keys.File] = true
+		fixedKeys[keys.Func] = true
+		if keys.Line != "" {
+			fixedKeys[keys.Line] = true
+		}
+	}
+
+	data := make(logrus.Fields, len(entry.Data)+5)
+	for key, val := range entry.Data {
+		if key == LOGGER_COMPONENT_FIELD_NAME {
+			key = keys.Comp
+		} else if fixedKeys[key] || key == keys.Comp {
+			key = JSON_CLASH_PREFIX + key
+		}
+		if err, ok := val.(error); ok {
+			val = err.Error()
+		}
+		data[key] = val
+	}
+
+	if ts := f.Timestamp(entry.Time); ts != nil {
+		data[keys.Time] = ts
+	}
+	levelName, ok := keys.LevelNames[entry.Level]
+	if !ok {
+		levelName = entry.Level.String()
+	}
+	data[keys.Level] = levelName
+	data[keys.Msg] = entry.Message
+	if entry.HasCaller() {
+		function, file := entry.Caller.Function, fmt.Sprintf("%s:%d", entry.Caller.File, entry.Caller.Line)
+		if f.CallerPrettyfier != nil {
+			function, file = f.CallerPrettyfier(entry.Caller)
+		}
+		f.addCaller(data, function, file)
+	}
+
+	b := entry.Buffer
+	if b == nil {
+		b = &bytes.Buffer{}
+	}
+	encoder := json.NewEncoder(b)
+	if err := encoder.Encode(data); err != nil {
+		return nil, fmt.Errorf("failed to marshal fields to JSON, %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+func (f *JsonFormatter) addCaller(data logrus.Fields, function, file string) {
+	keys := f.Keys
+	line := ""
+	if keys.SourceLocation != "" || keys.Line != "" {
+		if i := strings.LastIndexByte(file, ':'); i >= 0 {
+			file, line = file[:i], file[i+1:]
+		}
+	}
+	if keys.SourceLocation != "" {
+		// The line# is a string, as per the Cloud Logging API, see
+		// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#LogEntrySourceLocation
+		sourceLocation := make(map[string]string, 3)
+		if file != "" {
+			sourceLocation["file"] = file
+		}
+		if line != "" {
+			sourceLocation["line"] = line
+		}
+		if function != "" {
+			sourceLocation["function"] = function
+		}
+		if len(sourceLocation) > 0 {
+			data[keys.SourceLocation] = sourceLocation
+		}
+		return
+	}
+	if function != "" {
+		data[keys.Func] = function
+	}
+	if file != "" {
+		data[keys.File] = file
+	}
+	if line != "" {
+		if lineNum, err := strconv.Atoi(line); err == nil {
+			data[keys.Line] = lineNum
+		} else {
+			data[keys.Line] = line
+		}
+	}
+}
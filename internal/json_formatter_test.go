@@ -0,0 +1,127 @@
+package logrusx_internal
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestJsonFormatter(t *testing.T) {
+	entryTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	frame := &runtime.Frame{File: "/src/module/pkg/file.go", Line: 13, PC: 1}
+	prettyfier := NewCallerPrettyfier()
+
+	for _, tc := range []struct {
+		name     string
+		preset   string
+		fieldMap map[string]string
+		level    logrus.Level
+		data     logrus.Fields
+		want     map[string]any
+	}{
+		{
+			name:     "map",
+			fieldMap: map[string]string{"msg": "message", "comp": "component", "line": "line"},
+			level:    logrus.InfoLevel,
+			data:     logrus.Fields{LOGGER_COMPONENT_FIELD_NAME: "comp", "k": "v", "message": "clash"},
+			want: map[string]any{
+				"time":           "2024-01-02T03:04:05Z",
+				"level":          "info",
+				"message":        "record",
+				"file":           "pkg/file.go",
+				"line":           float64(13),
+				"component":      "comp",
+				"k":              "v",
+				"fields.message": "clash",
+			},
+		},
+		{
+			name:   "ecs",
+			preset: JSON_FIELD_PRESET_ECS,
+			level:  logrus.WarnLevel,
+			data:   logrus.Fields{LOGGER_COMPONENT_FIELD_NAME: "comp", "err": errors.New("failed")},
+			want: map[string]any{
+				"@timestamp":           "2024-01-02T03:04:05Z",
+				"log.level":            "warning",
+				"message":              "record",
+				"log.origin.file.name": "pkg/file.go",
+				"log.origin.file.line": float64(13),
+				"log.logger":           "comp",
+				"err":                  "failed",
+			},
+		},
+		{
+			name:   "gcp",
+			preset: JSON_FIELD_PRESET_GCP,
+			level:  logrus.FatalLevel,
+			data:   logrus.Fields{LOGGER_COMPONENT_FIELD_NAME: "comp", "time": "clash"},
+			want: map[string]any{
+				"time":     "2024-01-02T03:04:05Z",
+				"severity": "CRITICAL",
+				"message":  "record",
+				"logging.googleapis.com/sourceLocation": map[string]any{
+					"file": "pkg/file.go",
+					"line": "13",
+				},
+				"component":   "comp",
+				"fields.time": "clash",
+			},
+		},
+		{
+			name:     "datadog",
+			preset:   JSON_FIELD_PRESET_DATADOG,
+			fieldMap: map[string]string{"comp": ""},
+			level:    logrus.WarnLevel,
+			data:     logrus.Fields{LOGGER_COMPONENT_FIELD_NAME: "comp"},
+			want: map[string]any{
+				"timestamp": "2024-01-02T03:04:05Z",
+				"status":    "warn",
+				"message":   "record",
+				"file":      "pkg/file.go:13",
+				"comp":      "comp",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			keys, err := NewJsonFieldKeys(tc.preset, tc.fieldMap)
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf, err := NewJsonFormatter(prettyfier.Pretiffy, nil, keys).Format(&logrus.Entry{
+				Time:    entryTime,
+				Level:   tc.level,
+				Message: "record",
+				Data:    tc.data,
+				Caller:  frame,
+				Logger:  &logrus.Logger{ReportCaller: true},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]any)
+			if err := json.Unmarshal(buf, &got); err != nil {
+				t.Fatalf("%q: %v", buf, err)
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("\nwant: %v\n got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNewJsonFieldKeys(t *testing.T) {
+	if keys, err := NewJsonFieldKeys("", nil); keys != nil || err != nil {
+		t.Errorf("want nil, nil, got %v, %v", keys, err)
+	}
+	if _, err := NewJsonFieldKeys("splunk", nil); err == nil {
+		t.Error("preset: want error, got nil")
+	}
+	if _, err := NewJsonFieldKeys("", map[string]string{"timestamp": "ts"}); err == nil {
+		t.Error("field map: want error, got nil")
+	}
+}
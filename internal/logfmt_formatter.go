@@ -0,0 +1,170 @@
+// logfmt formatter, see https://brandur.org/logfmt
+
+package logrusx_internal
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Format the entry as a single line of key=value pairs, sorted via
+// LogFieldKeyOrder. The values are quoted only when needed, i.e. when empty or
+// when containing spaces, '=', '"', '\' or control chars, the latter being
+// escaped. The keys are sanitized, the invalid chars being replaced by '_'.
+type LogfmtFormatter struct {
+	CallerPrettyfier func(*runtime.Frame) (function string, file string)
+	// Return nil to disable the timestamp:
+	Timestamp func(time.Time) any
+	KeyOrder  *LogFieldKeyOrder
+}
+
+func NewLogfmtFormatter(
+	callerPrettyfier func(*runtime.Frame) (string, string),
+	timestamp func(time.Time) any,
+	keyOrder *LogFieldKeyOrder,
+) *LogfmtFormatter {
+	if timestamp == nil {
+		timestamp = DefaultTimestampFormat
+	}
+	if keyOrder == nil {
+		keyOrder = defaultLogFieldKeyOrder
+	}
+	return &LogfmtFormatter{
+		CallerPrettyfier: callerPrettyfier,
+		Timestamp:        timestamp,
+		KeyOrder:         keyOrder,
+	}
+}
+
+// The prefix for the fields clashing w/ the fixed ones (time, level, msg,
+// etc), as per logrus convention:
+const LOGFMT_CLASH_PREFIX = "fields."
+
+var logfmtFixedKeys = map[string]bool{
+	logrus.FieldKeyTime:  true,
+	logrus.FieldKeyLevel: true,
+	logrus.FieldKeyMsg:   true,
+	logrus.FieldKeyFile:  true,
+	logrus.FieldKeyFunc:  true,
+}
+
+func (f *LogfmtFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	b := entry.Buffer
+	if b == nil {
+		b = &bytes.Buffer{}
+	}
+
+	values := make(map[string]any, len(entry.Data)+5)
+	for key, val := range entry.Data {
+		if logfmtFixedKeys[key] {
+			key = LOGFMT_CLASH_PREFIX + key
+		}
+		if err, ok := val.(error); ok {
+			val = err.Error()
+		}
+		values[key] = val
+	}
+	if ts := f.Timestamp(entry.Time); ts != nil {
+		values[logrus.FieldKeyTime] = ts
+	}
+	values[logrus.FieldKeyLevel] = entry.Level.String()
+	values[logrus.FieldKeyMsg] = entry.Message
+	if entry.HasCaller() {
+		function, file := entry.Caller.Function, fmt.Sprintf("%s:%d", entry.Caller.File, entry.Caller.Line)
+		if f.CallerPrettyfier != nil {
+			function, file = f.CallerPrettyfier(entry.Caller)
+		}
+		if function != "" {
+			values[logrus.FieldKeyFunc] = function
+		}
+		if file != "" {
+			values[logrus.FieldKeyFile] = file
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	f.KeyOrder.Sort(keys)
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		writeLogfmtKey(b, key)
+		b.WriteByte('=')
+		val, ok := values[key].(string)
+		if !ok {
+			val = fmt.Sprint(values[key])
+		}
+		writeLogfmtValue(b, val)
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+func isLogfmtKeyByte(c byte) bool {
+	return c > ' ' && c != '=' && c != '"' && c != '\\' && c < utf8.RuneSelf && c != 0x7f
+}
+
+func writeLogfmtKey(b *bytes.Buffer, key string) {
+	if key == "" {
+		b.WriteByte('_')
+		return
+	}
+	for i := 0; i < len(key); i++ {
+		if c := key[i]; isLogfmtKeyByte(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+}
+
+func logfmtNeedsQuoting(val string) bool {
+	if val == "" {
+		return true
+	}
+	for _, r := range val {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == 0x7f || r == utf8.RuneError ||
+			(0x80 <= r && r < 0xa0) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeLogfmtValue(b *bytes.Buffer, val string) {
+	if !logfmtNeedsQuoting(val) {
+		b.WriteString(val)
+		return
+	}
+	b.WriteByte('"')
+	for i := 0; i < len(val); {
+		r, size := utf8.DecodeRuneInString(val[i:])
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == utf8.RuneError && size == 1:
+			b.WriteString(`\ufffd`)
+		case r < ' ' || r == 0x7f || (0x80 <= r && r < 0xa0):
+			fmt.Fprintf(b, `\u%04x`, r)
+		default:
+			b.WriteString(val[i : i+size])
+		}
+		i += size
+	}
+	b.WriteByte('"')
+}
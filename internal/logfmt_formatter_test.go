@@ -0,0 +1,151 @@
+package logrusx_internal
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+type logfmtPair struct {
+	key, val string
+}
+
+// A strict logfmt parser: unquoted values may not contain spaces, '=', '"' or
+// '\', quoted values use Go escaping.
+func parseLogfmt(line string) ([]logfmtPair, error) {
+	pairs := make([]logfmtPair, 0)
+	for line != "" {
+		i := strings.IndexByte(line, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("missing key at %q", line)
+		}
+		key := line[:i]
+		if strings.ContainsAny(key, " \"\\") {
+			return nil, fmt.Errorf("invalid key %q", key)
+		}
+		line = line[i+1:]
+		var val string
+		if strings.HasPrefix(line, `"`) {
+			quoted, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted value at %q: %v", line, err)
+			}
+			if val, err = strconv.Unquote(quoted); err != nil {
+				return nil, err
+			}
+			line = line[len(quoted):]
+		} else {
+			j := strings.IndexByte(line, ' ')
+			if j < 0 {
+				j = len(line)
+			}
+			val = line[:j]
+			if val == "" || strings.ContainsAny(val, "=\"\\") {
+				return nil, fmt.Errorf("invalid unquoted value %q", val)
+			}
+			line = line[j:]
+		}
+		pairs = append(pairs, logfmtPair{key, val})
+		if line != "" {
+			if line[0] != ' ' {
+				return nil, fmt.Errorf("missing separator at %q", line)
+			}
+			line = line[1:]
+		}
+	}
+	return pairs, nil
+}
+
+func TestLogfmtFormatter(t *testing.T) {
+	entryTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	frame := &runtime.Frame{File: "/src/module/pkg/file.go", Line: 13, PC: 1}
+	prettyfier := NewCallerPrettyfier()
+
+	for _, tc := range []struct {
+		name      string
+		msg       string
+		data      logrus.Fields
+		wantPairs []logfmtPair
+	}{
+		{
+			name: "plain",
+			msg:  "hello",
+			data: logrus.Fields{"b": 2, "a": "x", LOGGER_COMPONENT_FIELD_NAME: "comp"},
+			wantPairs: []logfmtPair{
+				{"time", "2024-01-02T03:04:05Z"},
+				{"level", "info"},
+				{"comp", "comp"},
+				{"file", "pkg/file.go:13"},
+				{"a", "x"},
+				{"b", "2"},
+				{"msg", "hello"},
+			},
+		},
+		{
+			name: "escaping",
+			msg:  "a \"quoted\"\nmulti\tline\\ msg",
+			data: logrus.Fields{
+				"empty":     "",
+				"eq":        "a=b",
+				"ctrl":      "bell\x07",
+				"utf8":      "naïve",
+				"bad-utf8":  "x\xffy",
+				"err":       errors.New("failed: no such file"),
+				"key w/ sp": 1,
+				"msg":       "clash",
+			},
+			wantPairs: []logfmtPair{
+				{"time", "2024-01-02T03:04:05Z"},
+				{"level", "info"},
+				{"file", "pkg/file.go:13"},
+				{"bad-utf8", "x" + string(utf8.RuneError) + "y"},
+				{"ctrl", "bell\x07"},
+				{"empty", ""},
+				{"eq", "a=b"},
+				{"err", "failed: no such file"},
+				{"fields.msg", "clash"},
+				{"key_w/_sp", "1"},
+				{"utf8", "naïve"},
+				{"msg", "a \"quoted\"\nmulti\tline\\ msg"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			entry := &logrus.Entry{
+				Time:    entryTime,
+				Level:   logrus.InfoLevel,
+				Message: tc.msg,
+				Data:    tc.data,
+				Caller:  frame,
+				Logger:  &logrus.Logger{ReportCaller: true},
+			}
+			buf, err := NewLogfmtFormatter(prettyfier.Pretiffy, nil, nil).Format(entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			line := string(buf)
+			if !strings.HasSuffix(line, "\n") || strings.Count(line, "\n") != 1 {
+				t.Fatalf("want single line, got %q", line)
+			}
+			gotPairs, err := parseLogfmt(strings.TrimSuffix(line, "\n"))
+			if err != nil {
+				t.Fatalf("parse %q: %v", line, err)
+			}
+			if len(gotPairs) != len(tc.wantPairs) {
+				t.Fatalf("want %d pairs, got %d: %q", len(tc.wantPairs), len(gotPairs), line)
+			}
+			for i, want := range tc.wantPairs {
+				if gotPairs[i] != want {
+					t.Errorf("pair# %d: want %q, got %q", i+1, want, gotPairs[i])
+				}
+			}
+		})
+	}
+}
@@ -28,12 +28,28 @@ type ModuleDirPathCache struct {
 	// If no prefix match is found, the number of directories to keep from the
 	// end of the path.
 	keepNDirs int
+	// Whether to detect the module root if no prefix match is found, see
+	// detectModuleRoot, and the cache of detected roots, by source dir, nil
+	// for the undetectable ones:
+	autoModuleRoot bool
+	moduleRoots    map[string]*moduleRoot
+	// Whether to prefix the paths relative to a detected root w/ the module
+	// path and whether to keep the version, if any, in the latter, e.g.
+	// github.com/org/mod@v1.2.3/pkg/file.go:
+	withModulePath    bool
+	withModuleVersion bool
+	// Module paths registered explicitly, sorted in reverse order by length;
+	// they are used for the root detection even if it is disabled:
+	modulePathList []string
 }
 
 func NewModuleDirPathCache() *ModuleDirPathCache {
 	return &ModuleDirPathCache{
-		prefixList: make([]string, 0),
-		keepNDirs:  1,
+		prefixList:     make([]string, 0),
+		keepNDirs:      1,
+		autoModuleRoot: true,
+		moduleRoots:    make(map[string]*moduleRoot),
+		modulePathList: make([]string, 0),
 	}
 }
 
@@ -58,7 +74,9 @@ func (c *ModuleDirPathCache) addPrefix(prefix string) error {
 	return nil
 }
 
-func (c *ModuleDirPathCache) stripPrefix(filePath string) string {
+// Strip the prefix from the file path, the function, if not empty, is used for
+// the module root detection.
+func (c *ModuleDirPathCache) stripPrefix(filePath string, function string) string {
 	// Check if the file name starts with any of the prefixes:
 	for _, prefix := range c.prefixList {
 		if strings.HasPrefix(filePath, prefix) {
@@ -66,6 +84,24 @@ func (c *ModuleDirPathCache) stripPrefix(filePath string) string {
 			return filePath[len(prefix):]
 		}
 	}
+	dir := path.Dir(filePath)
+	root, ok := c.moduleRoots[dir]
+	if !ok {
+		root = c.findModuleRoot(dir, function)
+		if c.moduleRoots == nil {
+			c.moduleRoots = make(map[string]*moduleRoot)
+		}
+		c.moduleRoots[dir] = root
+	}
+	if root != nil && strings.HasPrefix(filePath, root.dir) {
+		if !c.withModulePath {
+			return filePath[len(root.dir):]
+		}
+		if c.withModuleVersion && root.version != "" {
+			return root.modulePath + "@" + root.version + "/" + filePath[len(root.dir):]
+		}
+		return root.modulePath + "/" + filePath[len(root.dir):]
+	}
 	// No prefix match, keep the last `keepNDirs` directories:
 	pathComp := strings.Split(filePath, "/")
 	keepNComps := c.keepNDirs + 1
@@ -78,10 +114,54 @@ func (c *ModuleDirPathCache) stripPrefix(filePath string) string {
 	return filePath
 }
 
+// Find the module root for a source dir: the explicitly registered module
+// paths are tried first, then, if enabled, the build info ones and go.mod.
+func (c *ModuleDirPathCache) findModuleRoot(dir string, function string) *moduleRoot {
+	buildInfoModulePaths, mainPkgPath := getBuildInfoModules()
+	if root := detectModuleRoot(dir, function, c.modulePathList, mainPkgPath); root != nil {
+		return root
+	}
+	if !c.autoModuleRoot {
+		return nil
+	}
+	if root := detectModuleRoot(dir, function, buildInfoModulePaths, mainPkgPath); root != nil {
+		return root
+	}
+	return findGoModRoot(dir)
+}
+
+func (c *ModuleDirPathCache) addModulePath(modulePath string) {
+	modulePath = strings.TrimSuffix(modulePath, "/")
+	for _, existing := range c.modulePathList {
+		if existing == modulePath {
+			return
+		}
+	}
+	c.modulePathList = append(c.modulePathList, modulePath)
+	sort.SliceStable(c.modulePathList, func(i, j int) bool {
+		return len(c.modulePathList[i]) > len(c.modulePathList[j])
+	})
+	// The detected roots may change:
+	c.moduleRoots = make(map[string]*moduleRoot)
+}
+
 func (c *ModuleDirPathCache) setKeepNDirs(n int) {
 	c.keepNDirs = n
 }
 
+func (c *ModuleDirPathCache) setAutoModuleRoot(autoModuleRoot bool) {
+	c.autoModuleRoot = autoModuleRoot
+	c.moduleRoots = make(map[string]*moduleRoot)
+}
+
+func (c *ModuleDirPathCache) setWithModulePath(withModulePath bool) {
+	c.withModulePath = withModulePath
+}
+
+func (c *ModuleDirPathCache) setWithModuleVersion(withModuleVersion bool) {
+	c.withModuleVersion = withModuleVersion
+}
+
 func (c *ModuleDirPathCache) addCallerSrcPathPrefix(upNDirs int, skip int) error {
 	skip += 1 // skip this function
 	_, file, _, ok := runtime.Caller(skip)
@@ -109,36 +189,102 @@ type LogFuncFilePair struct {
 	file     string
 }
 
+// Function name modes, e.g. for github.com/org/mod/pkg.(*Type).Method:
+const (
+	// No function name:
+	FUNC_NAME_OFF = "off"
+	// pkg.(*Type).Method:
+	FUNC_NAME_SHORT = "short"
+	// (*Type).Method:
+	FUNC_NAME_METHOD = "method"
+	// github.com/org/mod/pkg.(*Type).Method:
+	FUNC_NAME_FULL = "full"
+)
+
+var FuncNameModes = []string{
+	FUNC_NAME_OFF,
+	FUNC_NAME_SHORT,
+	FUNC_NAME_METHOD,
+	FUNC_NAME_FULL,
+}
+
+func IsValidFuncNameMode(mode string) bool {
+	for _, validMode := range FuncNameModes {
+		if mode == validMode {
+			return true
+		}
+	}
+	return false
+}
+
+// Format the function name, as reported by the runtime, based on the mode; an
+// unknown mode is equivalent to off.
+func formatFuncName(function string, mode string) string {
+	// The dots in the last path element are escaped, e.g. gopkg.in/yaml%2ev3:
+	lastSlash := strings.LastIndexByte(function, '/')
+	switch mode {
+	case FUNC_NAME_SHORT:
+		function = function[lastSlash+1:]
+	case FUNC_NAME_METHOD:
+		function = function[lastSlash+1:]
+		if dot := strings.IndexByte(function, '.'); dot >= 0 {
+			function = function[dot+1:]
+		}
+	case FUNC_NAME_FULL:
+	default:
+		return ""
+	}
+	return strings.ReplaceAll(function, "%2e", ".")
+}
+
 type CallerPrettyfier struct {
+	// The mutex serializes the cache misses and the settings changes, the
+	// cache hits are lock-free:
 	m                  *sync.Mutex
-	funcFileCache      map[uintptr]*LogFuncFilePair
+	funcFileCache      *CallerCache
 	moduleDirPathCache *ModuleDirPathCache
+	funcNameMode       string
 }
 
 func NewCallerPrettyfier() *CallerPrettyfier {
 	return &CallerPrettyfier{
 		m:                  &sync.Mutex{},
-		funcFileCache:      make(map[uintptr]*LogFuncFilePair),
+		funcFileCache:      NewCallerCache(CALLER_CACHE_MAX_SIZE_DEFAULT),
 		moduleDirPathCache: NewModuleDirPathCache(),
+		funcNameMode:       FUNC_NAME_OFF,
 	}
 }
 
-// Return the function name and filename:line# info from the frame. The filename is
-// relative to the source root dir.
+// Return the function name, formatted based on the function name mode, and
+// filename:line# info from the frame. The filename is relative to the source
+// root dir.
 func (p *CallerPrettyfier) Pretiffy(f *runtime.Frame) (function string, file string) {
+	if funcFile := p.funcFileCache.Load(f.PC); funcFile != nil {
+		return funcFile.function, funcFile.file
+	}
 	p.m.Lock()
 	defer p.m.Unlock()
-	funcFile := p.funcFileCache[f.PC]
+	// It may have been added while waiting for the lock:
+	funcFile := p.funcFileCache.Load(f.PC)
 	if funcFile == nil {
 		funcFile = &LogFuncFilePair{
-			"", //f.Function,
-			fmt.Sprintf("%s:%d", p.moduleDirPathCache.stripPrefix(f.File), f.Line),
+			formatFuncName(f.Function, p.funcNameMode),
+			fmt.Sprintf("%s:%d", p.moduleDirPathCache.stripPrefix(f.File, f.Function), f.Line),
 		}
-		p.funcFileCache[f.PC] = funcFile
+		p.funcFileCache.Store(f.PC, funcFile)
 	}
 	return funcFile.function, funcFile.file
 }
 
+// Set the max number of cached callers, use 0 for unbounded:
+func (p *CallerPrettyfier) SetCacheMaxSize(maxSize int) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.funcFileCache.MaxSize() != maxSize {
+		p.funcFileCache.SetMaxSize(maxSize)
+	}
+}
+
 func (p *CallerPrettyfier) AddCallerSrcPathPrefix(upNDirs int, skip int) error {
 	p.m.Lock()
 	defer p.m.Unlock()
@@ -151,21 +297,117 @@ func (p *CallerPrettyfier) SetKeepNDirs(n int) {
 	p.moduleDirPathCache.setKeepNDirs(n)
 }
 
-var LogFieldKeySortOrder = map[string]int{
-	// The desired order is time, level, file, func, other fields sorted
-	// alphabetically and msg. Use negative numbers for the fields preceding
-	// `other' to capitalize on the fact that any of the latter will return 0 at
-	// lookup.
-	logrus.FieldKeyTime:         -5,
-	logrus.FieldKeyLevel:        -4,
-	LOGGER_COMPONENT_FIELD_NAME: -3,
-	logrus.FieldKeyFile:         -2,
-	logrus.FieldKeyFunc:         -1,
-	logrus.FieldKeyMsg:          1,
+// Changing the settings below invalidates the cache, since they affect the
+// already prettyfied paths.
+func (p *CallerPrettyfier) SetAutoModuleRoot(autoModuleRoot bool) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.moduleDirPathCache.autoModuleRoot != autoModuleRoot {
+		p.moduleDirPathCache.setAutoModuleRoot(autoModuleRoot)
+		p.funcFileCache.Reset()
+	}
+}
+
+func (p *CallerPrettyfier) SetWithModulePath(withModulePath bool) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.moduleDirPathCache.withModulePath != withModulePath {
+		p.moduleDirPathCache.setWithModulePath(withModulePath)
+		p.funcFileCache.Reset()
+	}
+}
+
+func (p *CallerPrettyfier) SetFuncNameMode(mode string) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.funcNameMode != mode {
+		p.funcNameMode = mode
+		p.funcFileCache.Reset()
+	}
+}
+
+func (p *CallerPrettyfier) SetWithModuleVersion(withModuleVersion bool) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.moduleDirPathCache.withModuleVersion != withModuleVersion {
+		p.moduleDirPathCache.setWithModuleVersion(withModuleVersion)
+		p.funcFileCache.Reset()
+	}
+}
+
+func (p *CallerPrettyfier) AddModulePath(modulePath string) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.moduleDirPathCache.addModulePath(modulePath)
+	p.funcFileCache.Reset()
+}
+
+// The field key order: the leading keys, in the given order, the other keys,
+// sorted alphabetically unless unsorted, and the trailing keys, in the given
+// order. It is immutable, such that it can be shared by formatters.
+type LogFieldKeyOrder struct {
+	// Use negative numbers for the leading keys and positive ones for the
+	// trailing ones to capitalize on the fact that any of the other keys will
+	// return 0 at lookup.
+	rank map[string]int
+	// Whether to leave the other keys in the order they were given in, i.e.
+	// the logrus.Fields map iteration order, which is unspecified:
+	unsorted bool
+}
+
+// The default order is time, level, comp, file, func, other fields sorted
+// alphabetically and msg:
+func DefaultLogFieldKeyOrderLists() (leading, trailing []string) {
+	leading = []string{
+		logrus.FieldKeyTime,
+		logrus.FieldKeyLevel,
+		LOGGER_COMPONENT_FIELD_NAME,
+		logrus.FieldKeyFile,
+		logrus.FieldKeyFunc,
+	}
+	trailing = []string{
+		logrus.FieldKeyMsg,
+	}
+	return
+}
+
+var defaultLogFieldKeyOrder = NewLogFieldKeyOrder(DefaultLogFieldKeyOrderLists())
+
+// Build a field key order; a key listed more than once keeps its first
+// position, the leading keys taking precedence over the trailing ones.
+func NewLogFieldKeyOrder(leading, trailing []string) *LogFieldKeyOrder {
+	rank := make(map[string]int, len(leading)+len(trailing))
+	for i, key := range leading {
+		if _, ok := rank[key]; !ok {
+			rank[key] = i - len(leading)
+		}
+	}
+	for i, key := range trailing {
+		if _, ok := rank[key]; !ok {
+			rank[key] = i + 1
+		}
+	}
+	return &LogFieldKeyOrder{rank: rank}
+}
+
+// Return a copy w/ the other keys unsorted:
+func (o *LogFieldKeyOrder) Unsorted() *LogFieldKeyOrder {
+	return &LogFieldKeyOrder{rank: o.rank, unsorted: true}
+}
+
+func (o *LogFieldKeyOrder) Sort(keys []string) {
+	if o.unsorted {
+		sort.SliceStable(keys, func(i, j int) bool {
+			return o.rank[keys[i]] < o.rank[keys[j]]
+		})
+	} else {
+		sort.Sort(&LogFieldKeySortable{keys, o.rank})
+	}
 }
 
 type LogFieldKeySortable struct {
 	keys []string
+	rank map[string]int
 }
 
 func (d *LogFieldKeySortable) Len() int {
@@ -174,7 +416,7 @@ func (d *LogFieldKeySortable) Len() int {
 
 func (d *LogFieldKeySortable) Less(i, j int) bool {
 	key_i, key_j := d.keys[i], d.keys[j]
-	order_i, order_j := LogFieldKeySortOrder[key_i], LogFieldKeySortOrder[key_j]
+	order_i, order_j := d.rank[key_i], d.rank[key_j]
 	if order_i != 0 || order_j != 0 {
 		return order_i < order_j
 	}
@@ -185,25 +427,87 @@ func (d *LogFieldKeySortable) Swap(i, j int) {
 	d.keys[i], d.keys[j] = d.keys[j], d.keys[i]
 }
 
+// Sort the keys using the default order:
 func LogSortFieldKeys(keys []string) {
-	sort.Sort(&LogFieldKeySortable{keys})
+	defaultLogFieldKeyOrder.Sort(keys)
+}
+
+// The formatter constructors take the caller prettyfier function, generally
+// CallerPrettyfier.Pretiffy, the timestamp function, generally
+// TimestampFormatter.Format, and, for the key=value based ones, the field key
+// order; the latter two may be nil for the builtin ones.
+//
+// The timestamp is added as a field, such that the formatting is not limited
+// to time layouts. The time key is mapped to an unused name, such that the
+// added field does not clash w/ it; the actual clashes are handled by
+// timestampedFormatter.
+const timestampFieldMapKey = "\x00" + logrus.FieldKeyTime
+
+type timestampedFormatter struct {
+	formatter logrus.Formatter
+	timestamp func(time.Time) any
+}
+
+func (f *timestampedFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	data := make(logrus.Fields, len(entry.Data)+1)
+	for key, val := range entry.Data {
+		if key == logrus.FieldKeyTime {
+			key = "fields." + key
+		}
+		data[key] = val
+	}
+	if ts := f.timestamp(entry.Time); ts != nil {
+		data[logrus.FieldKeyTime] = ts
+	}
+	timestampedEntry := *entry
+	timestampedEntry.Data = data
+	return f.formatter.Format(&timestampedEntry)
+}
+
+func newTimestampedFormatter(formatter logrus.Formatter, timestamp func(time.Time) any) logrus.Formatter {
+	if timestamp == nil {
+		timestamp = DefaultTimestampFormat
+	}
+	return &timestampedFormatter{formatter, timestamp}
 }
 
-func NewTextFormatter(pretyffier *CallerPrettyfier) *logrus.TextFormatter {
-	return &logrus.TextFormatter{
-		DisableColors:    true,
-		DisableQuote:     false,
-		FullTimestamp:    true,
-		TimestampFormat:  LOGGER_TIMESTAMP_FORMAT,
-		CallerPrettyfier: pretyffier.Pretiffy,
-		DisableSorting:   false,
-		SortingFunc:      LogSortFieldKeys,
+func NewTextFormatter(
+	callerPrettyfier func(*runtime.Frame) (string, string),
+	timestamp func(time.Time) any,
+	keyOrder *LogFieldKeyOrder,
+) logrus.Formatter {
+	if keyOrder == nil {
+		keyOrder = defaultLogFieldKeyOrder
 	}
+	return newTimestampedFormatter(
+		&logrus.TextFormatter{
+			DisableColors:    true,
+			DisableQuote:     false,
+			DisableTimestamp: true,
+			FieldMap:         logrus.FieldMap{logrus.FieldKeyTime: timestampFieldMapKey},
+			CallerPrettyfier: callerPrettyfier,
+			DisableSorting:   false,
+			SortingFunc:      keyOrder.Sort,
+		},
+		timestamp,
+	)
 }
 
-func NewJsonFormatter(pretyffier *CallerPrettyfier) *logrus.JSONFormatter {
-	return &logrus.JSONFormatter{
-		TimestampFormat:  LOGGER_TIMESTAMP_FORMAT,
-		CallerPrettyfier: pretyffier.Pretiffy,
+// The keys may be nil for the logrus ones, see NewJsonFieldKeys.
+func NewJsonFormatter(
+	callerPrettyfier func(*runtime.Frame) (string, string),
+	timestamp func(time.Time) any,
+	keys *JsonFieldKeys,
+) logrus.Formatter {
+	if keys != nil {
+		return newJsonFormatter(callerPrettyfier, timestamp, keys)
 	}
+	return newTimestampedFormatter(
+		&logrus.JSONFormatter{
+			DisableTimestamp: true,
+			FieldMap:         logrus.FieldMap{logrus.FieldKeyTime: timestampFieldMapKey},
+			CallerPrettyfier: callerPrettyfier,
+		},
+		timestamp,
+	)
 }
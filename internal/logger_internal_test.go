@@ -1,6 +1,7 @@
 package logrusx_internal
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -17,7 +18,7 @@ func testLogAddModuleDirPathPrefix(t *testing.T, mdpc *ModuleDirPathCache, prefi
 }
 
 func testLogStripModuleDirPathPrefix(t *testing.T, mdpc *ModuleDirPathCache, filePath string, expected string) {
-	result := mdpc.stripPrefix(filePath)
+	result := mdpc.stripPrefix(filePath, "")
 	if result != expected {
 		t.Errorf("%#v: stripPrefix(%#v): want %#v, got %#v", mdpc, filePath, expected, result)
 	}
@@ -72,3 +73,66 @@ func TestStripPrefixNoMatch(t *testing.T) {
 		testLogStripModuleDirPathPrefix(t, &ModuleDirPathCache{keepNDirs: tc.keepNDirs}, tc.filePath, tc.expected)
 	}
 }
+
+func TestLogFieldKeyOrder(t *testing.T) {
+	keys := []string{"msg", "b", "request_id", "level", "a", "time", "c"}
+	for _, tc := range []struct {
+		name     string
+		keyOrder *LogFieldKeyOrder
+		want     []string
+	}{
+		{
+			"default",
+			NewLogFieldKeyOrder(DefaultLogFieldKeyOrderLists()),
+			[]string{"time", "level", "a", "b", "c", "request_id", "msg"},
+		},
+		{
+			"custom",
+			NewLogFieldKeyOrder([]string{"request_id", "level", "time"}, []string{"msg", "a", "level"}),
+			[]string{"request_id", "level", "time", "b", "c", "msg", "a"},
+		},
+		{
+			"empty",
+			NewLogFieldKeyOrder(nil, nil),
+			[]string{"a", "b", "c", "level", "msg", "request_id", "time"},
+		},
+		{
+			"unsorted",
+			NewLogFieldKeyOrder([]string{"time"}, []string{"msg"}).Unsorted(),
+			[]string{"time", "b", "request_id", "level", "a", "c", "msg"},
+		},
+	} {
+		got := append([]string(nil), keys...)
+		tc.keyOrder.Sort(got)
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%s: want %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestFormatFuncName(t *testing.T) {
+	for _, function := range []struct {
+		name                string
+		short, method, full string
+	}{
+		{"github.com/org/mod/pkg.(*Type).Method", "pkg.(*Type).Method", "(*Type).Method", "github.com/org/mod/pkg.(*Type).Method"},
+		{"github.com/org/mod/pkg.Func.func1", "pkg.Func.func1", "Func.func1", "github.com/org/mod/pkg.Func.func1"},
+		{"gopkg.in/yaml%2ev3.Marshal", "yaml.v3.Marshal", "Marshal", "gopkg.in/yaml.v3.Marshal"},
+		{"main.main", "main.main", "main", "main.main"},
+	} {
+		for _, tc := range []struct {
+			mode string
+			want string
+		}{
+			{FUNC_NAME_OFF, ""},
+			{"", ""},
+			{FUNC_NAME_SHORT, function.short},
+			{FUNC_NAME_METHOD, function.method},
+			{FUNC_NAME_FULL, function.full},
+		} {
+			if got := formatFuncName(function.name, tc.mode); got != tc.want {
+				t.Errorf("formatFuncName(%q, %q): want %q, got %q", function.name, tc.mode, tc.want, got)
+			}
+		}
+	}
+}
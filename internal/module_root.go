@@ -0,0 +1,201 @@
+// Automatic module root detection, for the source file path stripping.
+
+package logrusx_internal
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type moduleRoot struct {
+	// The module root dir, w/ a trailing `/`:
+	dir string
+	// The module path, e.g. github.com/org/mod:
+	modulePath string
+	// The version, if the root dir has one, e.g. github.com/org/mod@v1.2.3:
+	version string
+}
+
+// The module paths from the build info, sorted in reverse order by length, and
+// the main package path, used for the functions of package main:
+var getBuildInfoModules = sync.OnceValues(func() ([]string, string) {
+	modulePaths := make([]string, 0)
+	mainPkgPath := ""
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		if buildInfo.Main.Path != "" {
+			modulePaths = append(modulePaths, buildInfo.Main.Path)
+		}
+		for _, dep := range buildInfo.Deps {
+			// The import path, rather than the replacement one, is the one
+			// found in the function names:
+			modulePaths = append(modulePaths, dep.Path)
+		}
+		mainPkgPath = buildInfo.Path
+	}
+	sort.SliceStable(modulePaths, func(i, j int) bool {
+		return len(modulePaths[i]) > len(modulePaths[j])
+	})
+	return modulePaths, mainPkgPath
+})
+
+// Extract the package path from a function name, e.g.
+// github.com/org/mod/pkg.(*Type).Method -> github.com/org/mod/pkg. The dots in
+// the last path element are escaped in function names, e.g.
+// gopkg.in/yaml%2ev3.Marshal.
+func funcPackagePath(function string) string {
+	lastSlash := strings.LastIndexByte(function, '/')
+	dot := strings.IndexByte(function[lastSlash+1:], '.')
+	if dot < 0 {
+		return ""
+	}
+	return strings.ReplaceAll(function[:lastSlash+1+dot], "%2e", ".")
+}
+
+// Build the root for a dir, w/ the version extracted from the last path
+// element, e.g. /home/user/go/pkg/mod/github.com/org/mod@v1.2.3:
+func newModuleRoot(rootDir string, modulePath string) *moduleRoot {
+	version := ""
+	if i := strings.LastIndexByte(rootDir, '@'); i > strings.LastIndexByte(rootDir, '/') {
+		version = rootDir[i+1:]
+	}
+	return &moduleRoot{rootDir + "/", modulePath, version}
+}
+
+// Detect the module root for a source dir, given the candidate module paths,
+// sorted in reverse order by length:
+//   - based on the dir itself, for module qualified dirs, as found in -trimpath
+//     builds, e.g. github.com/org/mod@v1.2.3/pkg, or in the module cache, e.g.
+//     /home/user/go/pkg/mod/github.com/org/mod@v1.2.3/pkg
+//   - based on the package path of a function from that dir, in which case the
+//     package sub path is removed from the dir.
+//
+// Return nil if the root cannot be determined.
+func detectModuleRoot(dir string, function string, modulePaths []string, mainPkgPath string) *moduleRoot {
+	for _, modulePath := range modulePaths {
+		if root := matchModuleQualifiedDir(dir, modulePath); root != nil {
+			return root
+		}
+	}
+	pkgPath := funcPackagePath(function)
+	if pkgPath == "main" {
+		pkgPath = mainPkgPath
+	}
+	// External test packages:
+	pkgPath = strings.TrimSuffix(pkgPath, "_test")
+	if pkgPath == "" {
+		return nil
+	}
+	for _, modulePath := range modulePaths {
+		if pkgPath != modulePath && !strings.HasPrefix(pkgPath, modulePath+"/") {
+			continue
+		}
+		rootDir := dir
+		if subPath := pkgPath[len(modulePath):]; subPath != "" {
+			if !strings.HasSuffix(dir, subPath) {
+				continue
+			}
+			rootDir = dir[:len(dir)-len(subPath)]
+		}
+		return newModuleRoot(rootDir, modulePath)
+	}
+	return nil
+}
+
+// Match a module qualified dir, see detectModuleRoot. The module cache uses
+// escaped paths, w/ the upper case letters replaced by `!` + lower case, so
+// both forms are tried.
+func matchModuleQualifiedDir(dir string, modulePath string) *moduleRoot {
+	qualifiedPaths := []string{modulePath}
+	if escapedPath := escapeModulePath(modulePath); escapedPath != modulePath {
+		qualifiedPaths = append(qualifiedPaths, escapedPath)
+	}
+	for _, qualifiedPath := range qualifiedPaths {
+		// W/o version, for the main module in -trimpath builds:
+		if dir == qualifiedPath || strings.HasPrefix(dir, qualifiedPath+"/") {
+			return newModuleRoot(qualifiedPath, modulePath)
+		}
+		// W/ version, at the start or after a `/`:
+		for i := 0; i < len(dir); {
+			j := strings.Index(dir[i:], qualifiedPath+"@")
+			if j < 0 {
+				break
+			}
+			j += i
+			if j == 0 || dir[j-1] == '/' {
+				rootEnd := j + len(qualifiedPath) + 1
+				if k := strings.IndexByte(dir[rootEnd:], '/'); k >= 0 {
+					rootEnd += k
+				} else {
+					rootEnd = len(dir)
+				}
+				return newModuleRoot(dir[:rootEnd], modulePath)
+			}
+			i = j + 1
+		}
+	}
+	return nil
+}
+
+func escapeModulePath(modulePath string) string {
+	escaped := strings.Builder{}
+	for _, r := range modulePath {
+		if 'A' <= r && r <= 'Z' {
+			escaped.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
+
+func findGoModRoot(dir string) *moduleRoot {
+	if !path.IsAbs(dir) {
+		return nil
+	}
+	for {
+		if modulePath := readGoModModulePath(path.Join(dir, "go.mod")); modulePath != "" {
+			if dir != "/" {
+				dir += "/"
+			}
+			return &moduleRoot{dir, modulePath, ""}
+		}
+		parent := path.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+// Return the module path from a go.mod file, "" if the file is missing or
+// invalid:
+func readGoModModulePath(goModPath string) string {
+	f, err := os.Open(goModPath)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "module" {
+			continue
+		}
+		modulePath := fields[1]
+		if unquoted, err := strconv.Unquote(modulePath); err == nil {
+			modulePath = unquoted
+		}
+		return modulePath
+	}
+	return ""
+}
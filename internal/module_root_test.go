@@ -0,0 +1,145 @@
+package logrusx_internal
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFuncPackagePath(t *testing.T) {
+	for _, tc := range []struct {
+		function string
+		want     string
+	}{
+		{"github.com/org/mod/pkg.Func", "github.com/org/mod/pkg"},
+		{"github.com/org/mod/pkg.(*Type).Method.func1", "github.com/org/mod/pkg"},
+		{"github.com/org/mod.Func[...]", "github.com/org/mod"},
+		{"gopkg.in/yaml%2ev3.Marshal", "gopkg.in/yaml.v3"},
+		{"main.main", "main"},
+		{"", ""},
+	} {
+		if got := funcPackagePath(tc.function); got != tc.want {
+			t.Errorf("funcPackagePath(%q): want %q, got %q", tc.function, tc.want, got)
+		}
+	}
+}
+
+func TestDetectModuleRoot(t *testing.T) {
+	// This module, from the build info:
+	pc, file, _, _ := runtime.Caller(0)
+	function := runtime.FuncForPC(pc).Name()
+	dir := path.Dir(file)
+	wantDir := path.Dir(dir) + "/"
+	root := (&ModuleDirPathCache{autoModuleRoot: true}).findModuleRoot(dir, function)
+	if root == nil || root.dir != wantDir || root.modulePath != "github.com/bgp59/logrusx" {
+		t.Errorf("build info: want {%q %q}, got %+v", wantDir, "github.com/bgp59/logrusx", root)
+	}
+
+	// A module missing from the build info, via go.mod:
+	modDir := filepath.ToSlash(t.TempDir())
+	if err := os.MkdirAll(modDir+"/pkg/sub", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	goMod := "// Test module\nmodule \"example.com/mod\" // comment\n\ngo 1.23\n"
+	if err := os.WriteFile(modDir+"/go.mod", []byte(goMod), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root = (&ModuleDirPathCache{autoModuleRoot: true}).findModuleRoot(modDir+"/pkg/sub", "example.com/other/pkg/sub.Func")
+	if root == nil || root.dir != modDir+"/" || root.modulePath != "example.com/mod" {
+		t.Errorf("go.mod: want {%q %q}, got %+v", modDir+"/", "example.com/mod", root)
+	}
+
+	// Neither:
+	if root = (&ModuleDirPathCache{autoModuleRoot: true}).findModuleRoot("/no/such/dir", "example.com/other/pkg.Func"); root != nil {
+		t.Errorf("undetectable: want nil, got %+v", root)
+	}
+}
+
+func TestStripPrefixModuleRoot(t *testing.T) {
+	pc, file, _, _ := runtime.Caller(0)
+	function := runtime.FuncForPC(pc).Name()
+
+	mdpc := NewModuleDirPathCache()
+	for _, tc := range []struct {
+		withModulePath bool
+		want           string
+	}{
+		{false, "internal/module_root_test.go"},
+		{true, "github.com/bgp59/logrusx/internal/module_root_test.go"},
+	} {
+		mdpc.setWithModulePath(tc.withModulePath)
+		if got := mdpc.stripPrefix(file, function); got != tc.want {
+			t.Errorf("withModulePath=%v: want %q, got %q", tc.withModulePath, tc.want, got)
+		}
+	}
+
+	// Explicit prefixes take precedence:
+	mdpc.addPrefix(path.Dir(file) + "/")
+	if got, want := mdpc.stripPrefix(file, function), "module_root_test.go"; got != want {
+		t.Errorf("explicit prefix: want %q, got %q", want, got)
+	}
+
+	// Disabled, the fallback applies:
+	mdpc = NewModuleDirPathCache()
+	mdpc.setAutoModuleRoot(false)
+	mdpc.setKeepNDirs(0)
+	if got, want := mdpc.stripPrefix(file, function), "module_root_test.go"; got != want {
+		t.Errorf("disabled: want %q, got %q", want, got)
+	}
+}
+
+func TestDetectModuleRootQualified(t *testing.T) {
+	modulePaths := []string{"github.com/org/mod/v2", "github.com/Org/Mod", "github.com/org/mod"}
+	for _, tc := range []struct {
+		dir      string
+		function string
+		want     *moduleRoot
+	}{
+		// -trimpath, main module:
+		{"github.com/org/mod/pkg", "", &moduleRoot{"github.com/org/mod/", "github.com/org/mod", ""}},
+		{"github.com/org/mod", "", &moduleRoot{"github.com/org/mod/", "github.com/org/mod", ""}},
+		// -trimpath, dependencies:
+		{"github.com/org/mod@v1.2.3/pkg/sub", "", &moduleRoot{"github.com/org/mod@v1.2.3/", "github.com/org/mod", "v1.2.3"}},
+		{"github.com/org/mod/v2@v2.0.1/pkg", "", &moduleRoot{"github.com/org/mod/v2@v2.0.1/", "github.com/org/mod/v2", "v2.0.1"}},
+		// Module cache, escaped:
+		{
+			"/home/user/go/pkg/mod/github.com/!org/!mod@v1.0.0/pkg", "",
+			&moduleRoot{"/home/user/go/pkg/mod/github.com/!org/!mod@v1.0.0/", "github.com/Org/Mod", "v1.0.0"},
+		},
+		// Not qualified, via function:
+		{"/src/mod/pkg/sub", "github.com/org/mod/pkg/sub.(*T).F", &moduleRoot{"/src/mod/", "github.com/org/mod", ""}},
+		{"/src/mod/other", "github.com/org/mod/pkg/sub.(*T).F", nil},
+		// No match:
+		{"github.com/org/module/pkg", "", nil},
+		{"/src/xgithub.com/org/mod@v1.2.3/pkg", "", nil},
+	} {
+		got := detectModuleRoot(tc.dir, tc.function, modulePaths, "")
+		if (got == nil) != (tc.want == nil) || got != nil && *got != *tc.want {
+			t.Errorf("detectModuleRoot(%q, %q): want %+v, got %+v", tc.dir, tc.function, tc.want, got)
+		}
+	}
+}
+
+func TestStripPrefixModulePath(t *testing.T) {
+	mdpc := NewModuleDirPathCache()
+	mdpc.setAutoModuleRoot(false)
+	mdpc.addModulePath("github.com/org/mod")
+	file := "github.com/org/mod@v1.2.3/pkg/file.go"
+	for _, tc := range []struct {
+		withModulePath, withModuleVersion bool
+		want                              string
+	}{
+		{false, false, "pkg/file.go"},
+		{false, true, "pkg/file.go"},
+		{true, false, "github.com/org/mod/pkg/file.go"},
+		{true, true, "github.com/org/mod@v1.2.3/pkg/file.go"},
+	} {
+		mdpc.setWithModulePath(tc.withModulePath)
+		mdpc.setWithModuleVersion(tc.withModuleVersion)
+		if got := mdpc.stripPrefix(file, ""); got != tc.want {
+			t.Errorf("withModulePath=%v, withModuleVersion=%v: want %q, got %q", tc.withModulePath, tc.withModuleVersion, tc.want, got)
+		}
+	}
+}
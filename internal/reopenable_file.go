@@ -0,0 +1,68 @@
+// Log file w/ external rotation support, e.g. via logrotate w/ create/move
+// semantics.
+
+package logrusx_internal
+
+import (
+	"os"
+	"sync"
+)
+
+// The file is opened in append mode and it can be reopened after it was moved
+// (or removed) by an external tool:
+type ReopenableFile struct {
+	Filename string
+
+	m    sync.Mutex
+	file *os.File
+}
+
+func (f *ReopenableFile) open() error {
+	file, err := os.OpenFile(f.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	f.file = file
+	return nil
+}
+
+func (f *ReopenableFile) Write(buf []byte) (int, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	return f.file.Write(buf)
+}
+
+// Reopen the file if the path no longer points to the open file, i.e. the file
+// was moved or removed. Return whether it was reopened or not.
+func (f *ReopenableFile) Reopen() (bool, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if f.file != nil {
+		openFi, err := f.file.Stat()
+		if err == nil {
+			if pathFi, err := os.Stat(f.Filename); err == nil && os.SameFile(openFi, pathFi) {
+				return false, nil
+			}
+		}
+		f.file.Close()
+		f.file = nil
+	}
+	return true, f.open()
+}
+
+func (f *ReopenableFile) Close() error {
+	f.m.Lock()
+	defer f.m.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
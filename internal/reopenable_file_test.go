@@ -0,0 +1,53 @@
+package logrusx_internal
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestReopenableFile(t *testing.T) {
+	dir := t.TempDir()
+	f := &ReopenableFile{Filename: path.Join(dir, "app.log")}
+	defer f.Close()
+
+	write := func(s string) {
+		if _, err := f.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkContent := func(fileName string, want string) {
+		buf, err := os.ReadFile(path.Join(dir, fileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != want {
+			t.Fatalf("%s content: want %q, got %q", fileName, want, string(buf))
+		}
+	}
+	reopen := func(want bool) {
+		reopened, err := f.Reopen()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reopened != want {
+			t.Fatalf("Reopen: want %v, got %v", want, reopened)
+		}
+	}
+
+	write("1\n")
+	reopen(false)
+	write("2\n")
+
+	// Move the file, as logrotate would, the records should go to the moved
+	// file until reopen:
+	if err := os.Rename(f.Filename, path.Join(dir, "app.log.1")); err != nil {
+		t.Fatal(err)
+	}
+	write("3\n")
+	reopen(true)
+	write("4\n")
+
+	checkContent("app.log.1", "1\n2\n3\n")
+	checkContent("app.log", "4\n")
+}
@@ -0,0 +1,351 @@
+// Log file w/ time based rotation, optionally combined w/ size based rotation.
+
+package logrusx_internal
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ROTATING_FILE_COMPRESS_SUFFIX = ".gz"
+
+	ROTATING_FILE_PERIOD_FORMAT_DAY    = "2006-01-02"
+	ROTATING_FILE_PERIOD_FORMAT_HOUR   = "2006-01-02T15"
+	ROTATING_FILE_PERIOD_FORMAT_MINUTE = "2006-01-02T15-04"
+	ROTATING_FILE_PERIOD_FORMAT_SECOND = "2006-01-02T15-04-05"
+)
+
+// The file is rotated at period boundaries, aligned to wall clock, and,
+// optionally, when it reaches a max size. The backup file names embed the
+// period start, e.g. for hourly rotation of app.log: app-2025-01-02T15.log; if
+// the file was rotated more than once during the same period then the names
+// will be suffixed with an index: app-2025-01-02T15.1.log, etc. The backup
+// files may be compressed, in which case they have an additional ".gz" suffix.
+type RotatingFile struct {
+	// The file path:
+	Filename string
+	// Max size, in bytes, use 0 to disable:
+	MaxSize int64
+	// How many backup files to keep, use 0 to keep all:
+	MaxBackups int
+	// How long to keep the backup files, based on their period end, use 0 to
+	// keep them regardless of age:
+	MaxAge time.Duration
+	// Whether to gzip the backup files:
+	Compress bool
+	// The rotation interval; intervals up to 24h are aligned to midnight,
+	// e.g. a 6h interval will rotate at 00:00, 06:00, 12:00 and 18:00, the
+	// longer ones to 0001-01-01T00:00. The boundaries are in wall clock time,
+	// i.e. on DST change days the periods spanning the change are 1h
+	// shorter/longer:
+	Interval time.Duration
+	// Whether to use UTC or local time for period alignment and names:
+	UTC bool
+	// The clock, use nil for time.Now; it may be replaced for testing:
+	Now func() time.Time
+
+	m           sync.Mutex
+	file        *os.File
+	size        int64
+	periodStart time.Time
+
+	// The backup files are compressed and pruned in the background, one
+	// mill at a time:
+	millMu sync.Mutex
+	millWg sync.WaitGroup
+}
+
+func (f *RotatingFile) now() time.Time {
+	now := time.Now
+	if f.Now != nil {
+		now = f.Now
+	}
+	if f.UTC {
+		return now().UTC()
+	}
+	return now().Local()
+}
+
+// Convert between a time and its wall clock time, the latter represented in
+// UTC, such that the arithmetic on it is not affected by DST changes:
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+func fromWallClock(wall time.Time, loc *time.Location) time.Time {
+	return time.Date(
+		wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), loc,
+	)
+}
+
+// Return the start of the period containing t:
+func (f *RotatingFile) getPeriodStart(t time.Time) time.Time {
+	if f.Interval <= 0 {
+		return time.Time{}
+	}
+	wall := wallClock(t)
+	if f.Interval > 24*time.Hour {
+		return fromWallClock(wall.Truncate(f.Interval), t.Location())
+	}
+	midnight := time.Date(wall.Year(), wall.Month(), wall.Day(), 0, 0, 0, 0, time.UTC)
+	return fromWallClock(midnight.Add(wall.Sub(midnight)/f.Interval*f.Interval), t.Location())
+}
+
+// Return the end of the period starting at periodStart, i.e. the start of the
+// next one:
+func (f *RotatingFile) getPeriodEnd(periodStart time.Time) time.Time {
+	wall := wallClock(periodStart)
+	wallEnd := wall.Add(f.Interval)
+	if f.Interval <= 24*time.Hour {
+		// The last period of the day ends at midnight, if the interval is not
+		// a divisor of 24h:
+		nextMidnight := time.Date(wall.Year(), wall.Month(), wall.Day()+1, 0, 0, 0, 0, time.UTC)
+		if wallEnd.After(nextMidnight) {
+			wallEnd = nextMidnight
+		}
+	}
+	return fromWallClock(wallEnd, periodStart.Location())
+}
+
+// The format of the period start in backup file names, based on the interval
+// resolution:
+func (f *RotatingFile) periodFormat() string {
+	switch {
+	case f.Interval%(24*time.Hour) == 0:
+		return ROTATING_FILE_PERIOD_FORMAT_DAY
+	case f.Interval%time.Hour == 0:
+		return ROTATING_FILE_PERIOD_FORMAT_HOUR
+	case f.Interval%time.Minute == 0:
+		return ROTATING_FILE_PERIOD_FORMAT_MINUTE
+	default:
+		return ROTATING_FILE_PERIOD_FORMAT_SECOND
+	}
+}
+
+// Split the file name into the prefix and suffix used for backup names:
+func (f *RotatingFile) backupPrefixSuffix() (string, string) {
+	ext := path.Ext(f.Filename)
+	return f.Filename[:len(f.Filename)-len(ext)] + "-", ext
+}
+
+// Return the first available backup name for a period:
+func (f *RotatingFile) backupName(periodStart time.Time) string {
+	prefix, ext := f.backupPrefixSuffix()
+	name := prefix + periodStart.Format(f.periodFormat())
+	backupName := name + ext
+	for i := 1; ; i++ {
+		_, err := os.Stat(backupName)
+		_, errCompressed := os.Stat(backupName + ROTATING_FILE_COMPRESS_SUFFIX)
+		if err != nil && errCompressed != nil {
+			return backupName
+		}
+		backupName = fmt.Sprintf("%s.%d%s", name, i, ext)
+	}
+}
+
+func (f *RotatingFile) Write(buf []byte) (int, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	now := f.now()
+	if f.Interval > 0 && !f.getPeriodStart(now).Equal(f.periodStart) ||
+		f.MaxSize > 0 && f.size > 0 && f.size+int64(len(buf)) > f.MaxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(buf)
+	f.size += int64(n)
+	return n, err
+}
+
+// Open the file for the 1st time; if it already exists and it is not empty,
+// rotate it based on its modification time.
+func (f *RotatingFile) open() error {
+	if fi, err := os.Stat(f.Filename); err == nil && fi.Size() > 0 {
+		modTime := fi.ModTime()
+		if f.UTC {
+			modTime = modTime.UTC()
+		}
+		f.periodStart = f.getPeriodStart(modTime)
+		if err := f.backup(); err != nil {
+			return err
+		}
+	}
+	return f.create()
+}
+
+func (f *RotatingFile) create() error {
+	file, err := os.OpenFile(f.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	f.file = file
+	f.size = 0
+	f.periodStart = f.getPeriodStart(f.now())
+	return nil
+}
+
+// Rename the current file to the backup name for the current period and start
+// the mill for compressing and pruning the backups:
+func (f *RotatingFile) backup() error {
+	if err := os.Rename(f.Filename, f.backupName(f.periodStart)); err != nil {
+		return err
+	}
+	f.millWg.Add(1)
+	go func() {
+		defer f.millWg.Done()
+		f.millMu.Lock()
+		defer f.millMu.Unlock()
+		f.mill()
+	}()
+	return nil
+}
+
+func (f *RotatingFile) rotate() error {
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return err
+		}
+		f.file = nil
+	}
+	if err := f.backup(); err != nil {
+		return err
+	}
+	return f.create()
+}
+
+// Force a rotation:
+func (f *RotatingFile) Rotate() error {
+	f.m.Lock()
+	defer f.m.Unlock()
+	if f.file == nil {
+		return f.open()
+	}
+	return f.rotate()
+}
+
+// A backup file name parsed into period start and index:
+type rotatingFileBackup struct {
+	name        string
+	periodStart time.Time
+	index       int
+	compressed  bool
+}
+
+// Return the backup files, newest first:
+func (f *RotatingFile) backupFiles() ([]*rotatingFileBackup, error) {
+	prefix, ext := f.backupPrefixSuffix()
+	dirEntries, err := os.ReadDir(path.Dir(f.Filename))
+	if err != nil {
+		return nil, err
+	}
+	prefix = path.Base(prefix)
+	periodFormat := f.periodFormat()
+	backups := make([]*rotatingFileBackup, 0)
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		backup := &rotatingFileBackup{name: name}
+		if strings.HasSuffix(name, ROTATING_FILE_COMPRESS_SUFFIX) {
+			name = name[:len(name)-len(ROTATING_FILE_COMPRESS_SUFFIX)]
+			backup.compressed = true
+		}
+		if dirEntry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		periodIndex := name[len(prefix) : len(name)-len(ext)]
+		if i := strings.LastIndexByte(periodIndex, '.'); i > 0 {
+			if _, err := fmt.Sscanf(periodIndex[i+1:], "%d", &backup.index); err == nil {
+				periodIndex = periodIndex[:i]
+			}
+		}
+		backup.periodStart, err = time.ParseInLocation(periodFormat, periodIndex, f.now().Location())
+		if err != nil {
+			// Not a backup file:
+			continue
+		}
+		backups = append(backups, backup)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].periodStart.Equal(backups[j].periodStart) {
+			return backups[i].periodStart.After(backups[j].periodStart)
+		}
+		return backups[i].index > backups[j].index
+	})
+	return backups, nil
+}
+
+// Compress and prune the backups:
+func (f *RotatingFile) mill() {
+	backups, err := f.backupFiles()
+	if err != nil {
+		return
+	}
+	dir := path.Dir(f.Filename)
+	minPeriodEnd := f.now().Add(-f.MaxAge)
+	for i, backup := range backups {
+		backupPath := path.Join(dir, backup.name)
+		if f.MaxBackups > 0 && i >= f.MaxBackups ||
+			f.MaxAge > 0 && f.getPeriodEnd(backup.periodStart).Before(minPeriodEnd) {
+			os.Remove(backupPath)
+		} else if f.Compress && !backup.compressed {
+			compressFile(backupPath)
+		}
+	}
+}
+
+// Compress a file into file.gz and remove the original; in case of error the
+// original is kept:
+func compressFile(filePath string) error {
+	in, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	compressedPath := filePath + ROTATING_FILE_COMPRESS_SUFFIX
+	out, err := os.OpenFile(compressedPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(compressedPath)
+		return err
+	}
+	return os.Remove(filePath)
+}
+
+// Close the file and wait for the background compression and pruning, if any,
+// to complete:
+func (f *RotatingFile) Close() error {
+	f.m.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.m.Unlock()
+	f.millWg.Wait()
+	return err
+}
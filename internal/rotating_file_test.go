@@ -0,0 +1,221 @@
+package logrusx_internal
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"testing"
+	"time"
+)
+
+type testClock struct {
+	t time.Time
+}
+
+func (c *testClock) now() time.Time {
+	return c.t
+}
+
+func listTestDir(t *testing.T, dir string) []string {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(dirEntries))
+	for i, dirEntry := range dirEntries {
+		names[i] = dirEntry.Name()
+	}
+	sort.Strings(names)
+	return names
+}
+
+func checkTestDir(t *testing.T, dir string, wantNames []string) {
+	names := listTestDir(t, dir)
+	if len(names) != len(wantNames) {
+		t.Fatalf("dir content: want %q, got %q", wantNames, names)
+	}
+	for i := range wantNames {
+		if names[i] != wantNames[i] {
+			t.Fatalf("dir content: want %q, got %q", wantNames, names)
+		}
+	}
+}
+
+func TestRotatingFilePeriodStart(t *testing.T) {
+	for _, tc := range []struct {
+		interval time.Duration
+		t        string
+		want     string
+	}{
+		{time.Hour, "2025-01-02T15:04:05Z", "2025-01-02T15:00:00Z"},
+		{24 * time.Hour, "2025-01-02T15:04:05Z", "2025-01-02T00:00:00Z"},
+		{6 * time.Hour, "2025-01-02T15:04:05Z", "2025-01-02T12:00:00Z"},
+		{15 * time.Minute, "2025-01-02T15:44:05Z", "2025-01-02T15:30:00Z"},
+		{time.Hour, "2025-01-02T15:04:05+02:00", "2025-01-02T15:00:00+02:00"},
+	} {
+		f := &RotatingFile{Interval: tc.interval}
+		tm, _ := time.Parse(time.RFC3339, tc.t)
+		want, _ := time.Parse(time.RFC3339, tc.want)
+		if got := f.getPeriodStart(tm); !got.Equal(want) {
+			t.Errorf("getPeriodStart(%s, %s): want %s, got %s", tc.interval, tc.t, want, got)
+		}
+	}
+}
+
+func TestRotatingFilePeriodStartDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	for _, tc := range []struct {
+		interval time.Duration
+		t        time.Time
+		want     time.Time
+		wantEnd  time.Time
+	}{
+		// 23h day, 2025-03-09 02:00 EST -> 03:00 EDT:
+		{
+			24 * time.Hour,
+			time.Date(2025, 3, 9, 23, 30, 0, 0, loc),
+			time.Date(2025, 3, 9, 0, 0, 0, 0, loc),
+			time.Date(2025, 3, 10, 0, 0, 0, 0, loc),
+		},
+		{
+			6 * time.Hour,
+			time.Date(2025, 3, 9, 13, 30, 0, 0, loc),
+			time.Date(2025, 3, 9, 12, 0, 0, 0, loc),
+			time.Date(2025, 3, 9, 18, 0, 0, 0, loc),
+		},
+		// 25h day, 2025-11-02 02:00 EDT -> 01:00 EST:
+		{
+			24 * time.Hour,
+			time.Date(2025, 11, 2, 23, 30, 0, 0, loc),
+			time.Date(2025, 11, 2, 0, 0, 0, 0, loc),
+			time.Date(2025, 11, 3, 0, 0, 0, 0, loc),
+		},
+		{
+			6 * time.Hour,
+			time.Date(2025, 11, 2, 6, 30, 0, 0, loc),
+			time.Date(2025, 11, 2, 6, 0, 0, 0, loc),
+			time.Date(2025, 11, 2, 12, 0, 0, 0, loc),
+		},
+		{
+			7 * time.Hour,
+			time.Date(2025, 11, 2, 22, 30, 0, 0, loc),
+			time.Date(2025, 11, 2, 21, 0, 0, 0, loc),
+			time.Date(2025, 11, 3, 0, 0, 0, 0, loc),
+		},
+		// Intervals over 24h are aligned to the local midnight:
+		{
+			48 * time.Hour,
+			time.Date(2025, 11, 2, 23, 30, 0, 0, loc),
+			time.Date(2025, 11, 2, 0, 0, 0, 0, loc),
+			time.Date(2025, 11, 4, 0, 0, 0, 0, loc),
+		},
+	} {
+		f := &RotatingFile{Interval: tc.interval}
+		got := f.getPeriodStart(tc.t)
+		if !got.Equal(tc.want) {
+			t.Errorf("getPeriodStart(%s, %s): want %s, got %s", tc.interval, tc.t, tc.want, got)
+		}
+		if gotEnd := f.getPeriodEnd(got); !gotEnd.Equal(tc.wantEnd) {
+			t.Errorf("getPeriodEnd(%s, %s): want %s, got %s", tc.interval, got, tc.wantEnd, gotEnd)
+		}
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	clock := &testClock{time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)}
+	f := &RotatingFile{
+		Filename:   path.Join(dir, "app.log"),
+		MaxSize:    10,
+		MaxBackups: 3,
+		Interval:   time.Hour,
+		UTC:        true,
+		Now:        clock.now,
+	}
+	defer f.Close()
+
+	write := func(s string) {
+		if _, err := f.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+		f.millWg.Wait()
+	}
+
+	write("12345\n")
+	checkTestDir(t, dir, []string{"app.log"})
+
+	// Size based rotation within the same period:
+	write("12345\n")
+	checkTestDir(t, dir, []string{"app-2025-01-02T15.log", "app.log"})
+	write("12345\n")
+	checkTestDir(t, dir, []string{"app-2025-01-02T15.1.log", "app-2025-01-02T15.log", "app.log"})
+
+	// Time based rotation:
+	clock.t = clock.t.Add(time.Hour)
+	write("1\n")
+	checkTestDir(t, dir, []string{
+		"app-2025-01-02T15.1.log", "app-2025-01-02T15.2.log", "app-2025-01-02T15.log", "app.log",
+	})
+
+	// Max backups:
+	clock.t = clock.t.Add(time.Hour)
+	write("1\n")
+	checkTestDir(t, dir, []string{
+		"app-2025-01-02T15.1.log", "app-2025-01-02T15.2.log", "app-2025-01-02T16.log", "app.log",
+	})
+
+	buf, err := os.ReadFile(f.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "1\n" {
+		t.Fatalf("content: want %q, got %q", "1\n", string(buf))
+	}
+}
+
+func TestRotatingFileCompressMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	clock := &testClock{time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)}
+	f := &RotatingFile{
+		Filename: path.Join(dir, "app.log"),
+		MaxAge:   2 * 24 * time.Hour,
+		Compress: true,
+		Interval: 24 * time.Hour,
+		UTC:      true,
+		Now:      clock.now,
+	}
+	defer f.Close()
+
+	for i := 0; i < 4; i++ {
+		if _, err := f.Write([]byte("test\n")); err != nil {
+			t.Fatal(err)
+		}
+		f.millWg.Wait()
+		clock.t = clock.t.Add(24 * time.Hour)
+	}
+	// The 01-02 period ended on 01-03 00:00, more than 2 days before now,
+	// 01-05 15:04:05:
+	checkTestDir(t, dir, []string{"app-2025-01-03.log.gz", "app-2025-01-04.log.gz", "app.log"})
+
+	in, err := os.Open(path.Join(dir, "app-2025-01-04.log.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "test\n" {
+		t.Fatalf("content: want %q, got %q", "test\n", string(buf))
+	}
+}
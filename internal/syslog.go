@@ -0,0 +1,385 @@
+// Syslog support: RFC 5424 and RFC 3164 message formatting and the transport
+// over unix sockets, UDP and TCP.
+
+package logrusx_internal
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	SYSLOG_RFC5424 = "rfc5424"
+	SYSLOG_RFC3164 = "rfc3164"
+
+	// The structured data ID used for the logrus fields; 32473 is the private
+	// enterprise number reserved for documentation (RFC 5612):
+	SYSLOG_SD_ID = "logrus@32473"
+
+	SYSLOG_RFC5424_TIMESTAMP_FORMAT = "2006-01-02T15:04:05.000000Z07:00"
+	SYSLOG_RFC3164_TIMESTAMP_FORMAT = time.Stamp
+
+	// RFC 5424 header field max lengths:
+	SYSLOG_HOSTNAME_MAX_LEN = 255
+	SYSLOG_APP_NAME_MAX_LEN = 48
+	SYSLOG_SD_NAME_MAX_LEN  = 32
+	// RFC 3164 tag max length:
+	SYSLOG_TAG_MAX_LEN = 32
+)
+
+// Severities, RFC 5424 section 6.2.1:
+const (
+	SYSLOG_SEVERITY_EMERG = iota
+	SYSLOG_SEVERITY_ALERT
+	SYSLOG_SEVERITY_CRIT
+	SYSLOG_SEVERITY_ERR
+	SYSLOG_SEVERITY_WARNING
+	SYSLOG_SEVERITY_NOTICE
+	SYSLOG_SEVERITY_INFO
+	SYSLOG_SEVERITY_DEBUG
+)
+
+var SyslogFacilityByName = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+func GetSyslogFacilityNames() []string {
+	names := make([]string, 0, len(SyslogFacilityByName))
+	for name := range SyslogFacilityByName {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return SyslogFacilityByName[names[i]] < SyslogFacilityByName[names[j]]
+	})
+	return names
+}
+
+func SyslogSeverity(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel:
+		return SYSLOG_SEVERITY_EMERG
+	case logrus.FatalLevel:
+		return SYSLOG_SEVERITY_CRIT
+	case logrus.ErrorLevel:
+		return SYSLOG_SEVERITY_ERR
+	case logrus.WarnLevel:
+		return SYSLOG_SEVERITY_WARNING
+	case logrus.InfoLevel:
+		return SYSLOG_SEVERITY_INFO
+	}
+	return SYSLOG_SEVERITY_DEBUG
+}
+
+// Format the entry as a syslog message, w/o any transport framing. For RFC 5424
+// the fields are carried as structured data, for RFC 3164 they are appended to
+// the message as key=value pairs.
+type SyslogFormatter struct {
+	// SYSLOG_RFC5424 (default) or SYSLOG_RFC3164:
+	RFC      string
+	Facility int
+	// Empty hostname is rendered as the nil value "-" for RFC 5424 and it is
+	// omitted for RFC 3164, which is the convention for the local syslog:
+	Hostname string
+	AppName  string
+	PID      int
+	// Used for the caller info, if enabled:
+	CallerPrettyfier *CallerPrettyfier
+	KeyOrder         *LogFieldKeyOrder
+}
+
+func NewSyslogFormatter(
+	rfc string,
+	facility int,
+	hostname, appName string,
+	pretyffier *CallerPrettyfier,
+	keyOrder *LogFieldKeyOrder,
+) *SyslogFormatter {
+	if keyOrder == nil {
+		keyOrder = defaultLogFieldKeyOrder
+	}
+	return &SyslogFormatter{
+		RFC:              rfc,
+		Facility:         facility,
+		Hostname:         hostname,
+		AppName:          appName,
+		PID:              os.Getpid(),
+		CallerPrettyfier: pretyffier,
+		KeyOrder:         keyOrder,
+	}
+}
+
+func (f *SyslogFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	b := entry.Buffer
+	if b == nil {
+		b = &bytes.Buffer{}
+	}
+
+	fields := make(logrus.Fields, len(entry.Data)+2)
+	for key, val := range entry.Data {
+		if err, ok := val.(error); ok {
+			val = err.Error()
+		}
+		fields[key] = val
+	}
+	if entry.HasCaller() {
+		function, file := entry.Caller.Function, fmt.Sprintf("%s:%d", entry.Caller.File, entry.Caller.Line)
+		if f.CallerPrettyfier != nil {
+			function, file = f.CallerPrettyfier.Pretiffy(entry.Caller)
+		}
+		if function != "" {
+			fields[logrus.FieldKeyFunc] = function
+		}
+		if file != "" {
+			fields[logrus.FieldKeyFile] = file
+		}
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	f.KeyOrder.Sort(keys)
+
+	pri := f.Facility*8 + SyslogSeverity(entry.Level)
+	if f.RFC == SYSLOG_RFC3164 {
+		fmt.Fprintf(b, "<%d>%s ", pri, entry.Time.Format(SYSLOG_RFC3164_TIMESTAMP_FORMAT))
+		if f.Hostname != "" {
+			b.WriteString(syslogHeaderValue(f.Hostname, SYSLOG_HOSTNAME_MAX_LEN))
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(b, "%s[%d]: ", syslogTag(f.AppName), f.PID)
+		b.WriteString(syslogMessage(entry.Message))
+		for _, key := range keys {
+			fmt.Fprintf(b, " %s=%s", key, syslogPairValue(fmt.Sprint(fields[key])))
+		}
+		return b.Bytes(), nil
+	}
+
+	fmt.Fprintf(
+		b, "<%d>1 %s %s %s %d - ",
+		pri,
+		entry.Time.Format(SYSLOG_RFC5424_TIMESTAMP_FORMAT),
+		syslogHeaderValue(f.Hostname, SYSLOG_HOSTNAME_MAX_LEN),
+		syslogHeaderValue(f.AppName, SYSLOG_APP_NAME_MAX_LEN),
+		f.PID,
+	)
+	if len(keys) == 0 {
+		b.WriteByte('-')
+	} else {
+		b.WriteString("[" + SYSLOG_SD_ID)
+		for _, key := range keys {
+			fmt.Fprintf(b, ` %s="%s"`, syslogSDName(key), syslogSDValue(fmt.Sprint(fields[key])))
+		}
+		b.WriteByte(']')
+	}
+	if entry.Message != "" {
+		b.WriteByte(' ')
+		b.WriteString(syslogMessage(entry.Message))
+	}
+	return b.Bytes(), nil
+}
+
+// Header fields are limited to printable US-ASCII, w/o spaces; empty values
+// are rendered as the nil value:
+func syslogHeaderValue(val string, maxLen int) string {
+	val = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return -1
+		}
+		return r
+	}, val)
+	if len(val) > maxLen {
+		val = val[:maxLen]
+	}
+	if val == "" {
+		return "-"
+	}
+	return val
+}
+
+// The RFC 3164 tag is alphanumeric:
+func syslogTag(appName string) string {
+	tag := strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '[' || r == ']' || r == ':' {
+			return -1
+		}
+		return r
+	}, appName)
+	if len(tag) > SYSLOG_TAG_MAX_LEN {
+		tag = tag[:SYSLOG_TAG_MAX_LEN]
+	}
+	if tag == "" {
+		return "-"
+	}
+	return tag
+}
+
+// SD-NAME is printable US-ASCII except '=', ' ', ']' and '"':
+func syslogSDName(name string) string {
+	return syslogHeaderValue(strings.Map(func(r rune) rune {
+		if r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, name), SYSLOG_SD_NAME_MAX_LEN)
+}
+
+// PARAM-VALUE must have '"', '\' and ']' escaped:
+var syslogSDValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+func syslogSDValue(val string) string {
+	return syslogSDValueReplacer.Replace(val)
+}
+
+// The message is a single line, the framing for stream transports may rely on
+// it:
+var syslogMessageReplacer = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+
+func syslogMessage(msg string) string {
+	return syslogMessageReplacer.Replace(msg)
+}
+
+func syslogPairValue(val string) string {
+	if val == "" || strings.ContainsAny(val, " \t\r\n\"=") {
+		return strconv.Quote(val)
+	}
+	return val
+}
+
+// Send each write, expected to be a complete syslog message, to the syslog
+// server. Datagram transports (unixgram, udp) send one message per datagram,
+// stream transports (unix, tcp) use octet counting framing (RFC 6587). The
+// connection is (re)established on demand, such that the writer survives
+// syslog server restarts.
+type SyslogWriter struct {
+	// As per net.Dial; for network "" the local syslog is used, see
+	// SyslogLocalPaths:
+	Network string
+	Address string
+
+	m      sync.Mutex
+	conn   net.Conn
+	stream bool
+}
+
+// The paths to try for the local syslog:
+var SyslogLocalPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+func isStreamNetwork(network string) bool {
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix":
+		return true
+	}
+	return false
+}
+
+func (w *SyslogWriter) connect() error {
+	if w.Network != "" {
+		conn, err := net.Dial(w.Network, w.Address)
+		if err != nil {
+			return err
+		}
+		w.conn, w.stream = conn, isStreamNetwork(w.Network)
+		return nil
+	}
+
+	addresses := SyslogLocalPaths
+	if w.Address != "" {
+		addresses = []string{w.Address}
+	}
+	var err error
+	for _, address := range addresses {
+		for _, network := range []string{"unixgram", "unix"} {
+			var conn net.Conn
+			if conn, err = net.Dial(network, address); err == nil {
+				w.conn, w.stream = conn, isStreamNetwork(network)
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("local syslog: %w", err)
+}
+
+func (w *SyslogWriter) write(buf []byte) error {
+	if w.stream {
+		framed := make([]byte, 0, len(buf)+8)
+		framed = strconv.AppendInt(framed, int64(len(buf)), 10)
+		framed = append(framed, ' ')
+		buf = append(framed, buf...)
+	}
+	_, err := w.conn.Write(buf)
+	return err
+}
+
+func (w *SyslogWriter) Write(buf []byte) (int, error) {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	// Discard the record terminator, if any, it is not part of the message:
+	msg := bytes.TrimRight(buf, "\n")
+	if w.conn != nil {
+		if err := w.write(msg); err == nil {
+			return len(buf), nil
+		}
+		// Retry once over a new connection:
+		w.conn.Close()
+		w.conn = nil
+	}
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	if err := w.write(msg); err != nil {
+		w.conn.Close()
+		w.conn = nil
+		return 0, err
+	}
+	return len(buf), nil
+}
+
+// Connect now, to report errors early:
+func (w *SyslogWriter) Connect() error {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.conn != nil {
+		return nil
+	}
+	return w.connect()
+}
+
+func (w *SyslogWriter) Close() error {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,18 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package logrusx_internal
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func IsTerminal(w io.Writer) bool {
+	if file, ok := w.(*os.File); ok {
+		_, err := unix.IoctlGetTermios(int(file.Fd()), unix.TIOCGETA)
+		return err == nil
+	}
+	return false
+}
@@ -0,0 +1,18 @@
+//go:build linux || aix || zos
+
+package logrusx_internal
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func IsTerminal(w io.Writer) bool {
+	if file, ok := w.(*os.File); ok {
+		_, err := unix.IoctlGetTermios(int(file.Fd()), unix.TCGETS)
+		return err == nil
+	}
+	return false
+}
@@ -0,0 +1,9 @@
+//go:build !(darwin || dragonfly || freebsd || netbsd || openbsd || linux || aix || zos || windows)
+
+package logrusx_internal
+
+import "io"
+
+func IsTerminal(w io.Writer) bool {
+	return false
+}
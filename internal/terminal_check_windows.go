@@ -0,0 +1,24 @@
+//go:build windows
+
+package logrusx_internal
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// The console is a terminal if it can be switched to virtual terminal mode,
+// such that it interprets the color escape sequences:
+func IsTerminal(w io.Writer) bool {
+	if file, ok := w.(*os.File); ok {
+		handle := windows.Handle(file.Fd())
+		var mode uint32
+		if err := windows.GetConsoleMode(handle, &mode); err != nil {
+			return false
+		}
+		return windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+// Timestamp formatting, shared by the formatters.
+
+package logrusx_internal
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Timestamp format names:
+const (
+	TIMESTAMP_RFC3339       = "rfc3339"
+	TIMESTAMP_RFC3339_MILLI = "rfc3339_milli"
+	TIMESTAMP_RFC3339_MICRO = "rfc3339_micro"
+	TIMESTAMP_RFC3339_NANO  = "rfc3339_nano"
+	TIMESTAMP_EPOCH         = "epoch"
+	TIMESTAMP_EPOCH_MILLI   = "epoch_milli"
+	TIMESTAMP_EPOCH_MICRO   = "epoch_micro"
+	TIMESTAMP_EPOCH_NANO    = "epoch_nano"
+)
+
+// The layouts use fixed width fractional seconds, unlike time.RFC3339Nano,
+// such that the timestamps sort lexicographically:
+var timestampLayouts = map[string]string{
+	TIMESTAMP_RFC3339:       time.RFC3339,
+	TIMESTAMP_RFC3339_MILLI: "2006-01-02T15:04:05.000Z07:00",
+	TIMESTAMP_RFC3339_MICRO: "2006-01-02T15:04:05.000000Z07:00",
+	TIMESTAMP_RFC3339_NANO:  "2006-01-02T15:04:05.000000000Z07:00",
+}
+
+var timestampEpochUnits = map[string]time.Duration{
+	TIMESTAMP_EPOCH:       time.Second,
+	TIMESTAMP_EPOCH_MILLI: time.Millisecond,
+	TIMESTAMP_EPOCH_MICRO: time.Microsecond,
+	TIMESTAMP_EPOCH_NANO:  time.Nanosecond,
+}
+
+var TimestampFormatNames = []string{
+	TIMESTAMP_RFC3339,
+	TIMESTAMP_RFC3339_MILLI,
+	TIMESTAMP_RFC3339_MICRO,
+	TIMESTAMP_RFC3339_NANO,
+	TIMESTAMP_EPOCH,
+	TIMESTAMP_EPOCH_MILLI,
+	TIMESTAMP_EPOCH_MICRO,
+	TIMESTAMP_EPOCH_NANO,
+}
+
+var timestampNameRe = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+type TimestampFormatter struct {
+	// Go time layout, if epochUnit is 0:
+	layout    string
+	epochUnit time.Duration
+	utc       bool
+	disabled  bool
+}
+
+// Build a timestamp formatter for a format name or a Go time layout; an empty
+// format stands for rfc3339.
+func NewTimestampFormatter(format string, utc bool, disabled bool) (*TimestampFormatter, error) {
+	f := &TimestampFormatter{utc: utc, disabled: disabled}
+	if format == "" {
+		format = TIMESTAMP_RFC3339
+	}
+	if layout, ok := timestampLayouts[format]; ok {
+		f.layout = layout
+	} else if epochUnit, ok := timestampEpochUnits[format]; ok {
+		f.epochUnit = epochUnit
+	} else {
+		// A layout should have at least one time element and it should not
+		// look like a name, which is a guard against misspelled names:
+		ref := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+		if ref.Format(format) == format || timestampNameRe.MatchString(format) {
+			return nil, fmt.Errorf(
+				"invalid timestamp format %q, must be one of %v or a Go time layout",
+				format, TimestampFormatNames,
+			)
+		}
+		f.layout = format
+	}
+	return f, nil
+}
+
+// Return nil if disabled, an int64 for epoch formats and a string otherwise.
+func (f *TimestampFormatter) Format(t time.Time) any {
+	if f.disabled {
+		return nil
+	}
+	if f.epochUnit > 0 {
+		return t.UnixNano() / int64(f.epochUnit)
+	}
+	if f.utc {
+		t = t.UTC()
+	}
+	return t.Format(f.layout)
+}
+
+// The default timestamp formatter, w/ the builtin LOGGER_TIMESTAMP_FORMAT:
+func DefaultTimestampFormat(t time.Time) any {
+	return t.Format(LOGGER_TIMESTAMP_FORMAT)
+}
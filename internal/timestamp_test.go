@@ -0,0 +1,45 @@
+package logrusx_internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimestampFormatter(t *testing.T) {
+	loc := time.FixedZone("TEST", 2*3600)
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 67008009, loc)
+
+	for _, tc := range []struct {
+		format   string
+		utc      bool
+		disabled bool
+		want     any
+	}{
+		{"", false, false, "2024-01-02T03:04:05+02:00"},
+		{TIMESTAMP_RFC3339, true, false, "2024-01-02T01:04:05Z"},
+		{TIMESTAMP_RFC3339_MILLI, false, false, "2024-01-02T03:04:05.067+02:00"},
+		{TIMESTAMP_RFC3339_MICRO, true, false, "2024-01-02T01:04:05.067008Z"},
+		{TIMESTAMP_RFC3339_NANO, false, false, "2024-01-02T03:04:05.067008009+02:00"},
+		{TIMESTAMP_EPOCH, false, false, ts.Unix()},
+		{TIMESTAMP_EPOCH_MILLI, true, false, ts.UnixMilli()},
+		{TIMESTAMP_EPOCH_MICRO, false, false, ts.UnixMicro()},
+		{TIMESTAMP_EPOCH_NANO, false, false, ts.UnixNano()},
+		{"Jan _2 15:04:05", true, false, "Jan  2 01:04:05"},
+		{TIMESTAMP_RFC3339, false, true, nil},
+	} {
+		f, err := NewTimestampFormatter(tc.format, tc.utc, tc.disabled)
+		if err != nil {
+			t.Errorf("format=%q: %v", tc.format, err)
+			continue
+		}
+		if got := f.Format(ts); got != tc.want {
+			t.Errorf("format=%q, utc=%v, disabled=%v: want %#v, got %#v", tc.format, tc.utc, tc.disabled, tc.want, got)
+		}
+	}
+
+	for _, format := range []string{"rfc3339_pico", "epoch_hours"} {
+		if _, err := NewTimestampFormatter(format, false, false); err == nil {
+			t.Errorf("format=%q: want error, got nil", format)
+		}
+	}
+}
@@ -3,31 +3,75 @@
 package logrusx
 
 import (
+	"fmt"
 	"io"
 	"os"
-	"path"
+	"sync"
+	"sync/atomic"
 
 	"github.com/sirupsen/logrus"
 
-	"gopkg.in/natefinch/lumberjack.v2"
-
 	logrusx_internal "github.com/bgp59/logrusx/internal"
 )
 
 const (
-	LOGGER_CONFIG_USE_JSON_DEFAULT                = true
-	LOGGER_CONFIG_LEVEL_DEFAULT                   = "info"
-	LOGGER_CONFIG_DISBALE_SRC_FILE_DEFAULT        = false
-	LOGGER_CONFIG_LOG_FILE_DEFAULT                = "" // i.e. stderr
-	LOGGER_CONFIG_LOG_FILE_MAX_SIZE_MB_DEFAULT    = 10
-	LOGGER_CONFIG_LOG_FILE_MAX_BACKUP_NUM_DEFAULT = 1
-
-	LOGGER_ARGS_USE_JSON                = "log-use-json"
-	LOGGER_ARGS_LEVEL                   = "log-level"
-	LOGGER_ARGS_DISABALE_SRC_FILE       = "log-disable-src-file"
-	LOGGER_ARGS_LOG_FILE                = "log-file"
-	LOGGER_ARGS_LOG_FILE_MAX_SIZE_MB    = "log-file-max-size-mb"
-	LOGGER_ARGS_LOG_FILE_MAX_BACKUP_NUM = "log-file-max-backup-num"
+	LOGGER_CONFIG_USE_JSON_DEFAULT                 = true
+	LOGGER_CONFIG_FORMAT_DEFAULT                   = "" // i.e. based on UseJson
+	LOGGER_CONFIG_TIMESTAMP_FORMAT_DEFAULT         = LOGGER_TIMESTAMP_RFC3339
+	LOGGER_CONFIG_TIMESTAMP_UTC_DEFAULT            = false
+	LOGGER_CONFIG_DISABLE_TIMESTAMP_DEFAULT        = false
+	LOGGER_CONFIG_JSON_FIELD_PRESET_DEFAULT        = "" // i.e. logrus keys
+	LOGGER_CONFIG_FIELD_ORDER_UNSORTED_DEFAULT     = false
+	LOGGER_CONFIG_LEVEL_DEFAULT                    = "info"
+	LOGGER_CONFIG_DISBALE_SRC_FILE_DEFAULT         = false
+	LOGGER_CONFIG_SRC_FILE_MODULE_PATH_DEFAULT     = false
+	LOGGER_CONFIG_SRC_FILE_MODULE_VERSION_DEFAULT  = false
+	LOGGER_CONFIG_FUNC_NAME_DEFAULT                = LOGGER_FUNC_NAME_OFF
+	LOGGER_CONFIG_CALLER_CACHE_MAX_SIZE_DEFAULT    = logrusx_internal.CALLER_CACHE_MAX_SIZE_DEFAULT
+	LOGGER_CONFIG_LOG_FILE_DEFAULT                 = "" // i.e. stderr
+	LOGGER_CONFIG_LOG_FILE_MAX_SIZE_MB_DEFAULT     = 10
+	LOGGER_CONFIG_LOG_FILE_MAX_BACKUP_NUM_DEFAULT  = 1
+	LOGGER_CONFIG_LOG_FILE_ROTATE_INTERVAL_DEFAULT = "" // i.e. size based only
+	LOGGER_CONFIG_LOG_FILE_ROTATE_UTC_DEFAULT      = false
+	LOGGER_CONFIG_LOG_FILE_MAX_AGE_DAYS_DEFAULT    = 0 // i.e. no age limit
+	LOGGER_CONFIG_LOG_FILE_COMPRESS_DEFAULT        = false
+	LOGGER_CONFIG_LOG_FILE_LOCAL_TIME_DEFAULT      = false
+	LOGGER_CONFIG_LOG_FILE_EXTERNAL_ROTATE_DEFAULT = false
+	LOGGER_CONFIG_ASYNC_DEFAULT                    = false
+	LOGGER_CONFIG_ASYNC_QUEUE_SIZE_DEFAULT         = 4096
+	LOGGER_CONFIG_ASYNC_OVERFLOW_POLICY_DEFAULT    = LOGGER_ASYNC_OVERFLOW_BLOCK
+	LOGGER_CONFIG_ASYNC_DROP_BELOW_LEVEL_DEFAULT   = "warn"
+	LOGGER_CONFIG_ASYNC_FLUSH_INTERVAL_DEFAULT     = "1s"
+
+	LOGGER_ARGS_USE_JSON                 = "log-use-json"
+	LOGGER_ARGS_FORMAT                   = "log-format"
+	LOGGER_ARGS_TIMESTAMP_FORMAT         = "log-timestamp-format"
+	LOGGER_ARGS_TIMESTAMP_UTC            = "log-timestamp-utc"
+	LOGGER_ARGS_DISABLE_TIMESTAMP        = "log-disable-timestamp"
+	LOGGER_ARGS_JSON_FIELD_PRESET        = "log-json-field-preset"
+	LOGGER_ARGS_FIELD_ORDER_LEADING      = "log-field-order-leading"
+	LOGGER_ARGS_FIELD_ORDER_TRAILING     = "log-field-order-trailing"
+	LOGGER_ARGS_FIELD_ORDER_UNSORTED     = "log-field-order-unsorted"
+	LOGGER_ARGS_LEVEL                    = "log-level"
+	LOGGER_ARGS_DISABALE_SRC_FILE        = "log-disable-src-file"
+	LOGGER_ARGS_SRC_FILE_MODULE_PATH     = "log-src-file-module-path"
+	LOGGER_ARGS_SRC_FILE_MODULE_VERSION  = "log-src-file-module-version"
+	LOGGER_ARGS_FUNC_NAME                = "log-func-name"
+	LOGGER_ARGS_CALLER_CACHE_MAX_SIZE    = "log-caller-cache-max-size"
+	LOGGER_ARGS_LOG_FILE                 = "log-file"
+	LOGGER_ARGS_LOG_FILE_MAX_SIZE_MB     = "log-file-max-size-mb"
+	LOGGER_ARGS_LOG_FILE_MAX_BACKUP_NUM  = "log-file-max-backup-num"
+	LOGGER_ARGS_LOG_FILE_ROTATE_INTERVAL = "log-file-rotate-interval"
+	LOGGER_ARGS_LOG_FILE_ROTATE_UTC      = "log-file-rotate-utc"
+	LOGGER_ARGS_LOG_FILE_MAX_AGE_DAYS    = "log-file-max-age-days"
+	LOGGER_ARGS_LOG_FILE_COMPRESS        = "log-file-compress"
+	LOGGER_ARGS_LOG_FILE_LOCAL_TIME      = "log-file-local-time"
+	LOGGER_ARGS_LOG_FILE_EXTERNAL_ROTATE = "log-file-external-rotate"
+	LOGGER_ARGS_ASYNC                    = "log-async"
+	LOGGER_ARGS_ASYNC_QUEUE_SIZE         = "log-async-queue-size"
+	LOGGER_ARGS_ASYNC_OVERFLOW_POLICY    = "log-async-overflow-policy"
+	LOGGER_ARGS_ASYNC_DROP_BELOW_LEVEL   = "log-async-drop-below-level"
+	LOGGER_ARGS_ASYNC_FLUSH_INTERVAL     = "log-async-flush-interval"
 
 	LOGGER_DEFAULT_LEVEL = logrus.InfoLevel
 )
@@ -45,127 +89,391 @@ type CollectableLogger struct {
 
 	// Caller prettyfier:
 	prettyfier *logrusx_internal.CallerPrettyfier
+	// The functions marked as logging helpers, see Helper:
+	callerHelpers *callerHelpers
+
+	// The config most recently applied via SetLogger. SetLogger calls are
+	// serialized via cfgMu:
+	cfgMu sync.Mutex
+	cfg   *LoggerConfig
+	// The signal channel for reopening externally rotated log files:
+	reopenSigCh chan os.Signal
+
+	// The primary output writer, shared by the root and the component loggers:
+	out *syncWriter
+	// The formatter shared by the root and the component loggers, it
+	// dispatches the records to all the outputs:
+	outputsFormatter *outputsFormatter
+	// The number of records dropped by the async writers no longer in use:
+	asyncDropped atomic.Uint64
+
+	// Component loggers, by name, and the levels set explicitly for them. The
+	// changes to the root logger settings are propagated to the component
+	// loggers under compMu:
+	compMu     sync.Mutex
+	comps      map[string]*compLogger
+	compLevels map[string]logrus.Level
 }
 
+// Get the primary output:
 func (logger *CollectableLogger) GetOutput() io.Writer {
-	return logger.Out
+	return logger.out.getOutput()
+}
+
+// Set the primary output:
+func (logger *CollectableLogger) SetOutput(out io.Writer) {
+	logger.out.setOutput(out)
+	if formatter, ok := logger.outputsFormatter.getOutputs()[0].formatter.(LoggerOutputAwareFormatter); ok {
+		formatter.SetOutput(out)
+	}
 }
 
 func (logger *CollectableLogger) GetLevel() any {
 	return logger.Logger.GetLevel()
 }
 
+// Set the root level; the component loggers w/o an explicit level will follow
+// it.
 func (logger *CollectableLogger) SetLevel(level any) {
 	if level, ok := level.(logrus.Level); ok {
+		logger.compMu.Lock()
+		defer logger.compMu.Unlock()
 		logger.Logger.SetLevel(level)
 		logger.IsEnabledForDebug = logger.IsLevelEnabled(logrus.DebugLevel)
+		for _, comp := range logger.comps {
+			if !comp.hasLevel {
+				comp.setLevel(level)
+			}
+		}
 	}
 }
 
+// Set the formatter for the primary output:
+func (logger *CollectableLogger) SetFormatter(formatter logrus.Formatter) {
+	logger.cfgMu.Lock()
+	defer logger.cfgMu.Unlock()
+	outputs := logger.outputsFormatter.getOutputs()
+	newOutputs := make([]*loggerOutput, len(outputs))
+	copy(newOutputs, outputs)
+	primary := *newOutputs[0]
+	primary.formatter = formatter
+	newOutputs[0] = &primary
+	logger.outputsFormatter.setOutputs(newOutputs)
+}
+
+func (logger *CollectableLogger) SetReportCaller(reportCaller bool) {
+	logger.compMu.Lock()
+	defer logger.compMu.Unlock()
+	logger.Logger.SetReportCaller(reportCaller)
+	for _, comp := range logger.comps {
+		comp.logger.SetReportCaller(reportCaller)
+	}
+}
+
+// Function name modes, see LoggerConfig.FuncName:
+const (
+	LOGGER_FUNC_NAME_OFF    = logrusx_internal.FUNC_NAME_OFF
+	LOGGER_FUNC_NAME_SHORT  = logrusx_internal.FUNC_NAME_SHORT
+	LOGGER_FUNC_NAME_METHOD = logrusx_internal.FUNC_NAME_METHOD
+	LOGGER_FUNC_NAME_FULL   = logrusx_internal.FUNC_NAME_FULL
+)
+
+var loggerFuncNameModes = logrusx_internal.FuncNameModes
+
+func isValidFuncNameMode(mode string) bool {
+	return logrusx_internal.IsValidFuncNameMode(mode)
+}
+
 type LoggerConfig struct {
 	// Whether to structure the logged record in JSON:
-	UseJson bool `yaml:"use_json"`
+	UseJson bool `yaml:"use_json" json:"use_json"`
+	// The record format: text, json, logfmt, console (colorized for terminals)
+	// or any other registered via RegisterLoggerFormat. If not empty, it
+	// supersedes UseJson:
+	Format string `yaml:"format" json:"format"`
+	// The timestamp format: rfc3339 (the default), rfc3339_milli,
+	// rfc3339_micro, rfc3339_nano, epoch, epoch_milli, epoch_micro,
+	// epoch_nano or a Go time layout. It does not apply to the console format.
+	TimestampFormat string `yaml:"timestamp_format" json:"timestamp_format"`
+	// Whether to use UTC rather than local time:
+	TimestampUTC bool `yaml:"timestamp_utc" json:"timestamp_utc"`
+	// Whether to omit the timestamp, e.g. for outputs adding their own:
+	DisableTimestamp bool `yaml:"disable_timestamp" json:"disable_timestamp"`
+	// The JSON field keys preset: ecs, gcp, datadog or empty for the logrus
+	// keys. Besides the keys, the presets may change the level names, e.g.
+	// gcp uses the Cloud Logging severities:
+	JsonFieldPreset string `yaml:"json_field_preset" json:"json_field_preset"`
+	// JSON field key overrides, applied on top of the preset, indexed by
+	// time, level, msg, file, line, func, comp or source_location. The line,
+	// if set, is split from the file and logged as a number; the source
+	// location, if set, groups file, line and function into an object. An
+	// empty key restores the logrus one, or disables line and source_location:
+	JsonFieldMap map[string]string `yaml:"json_field_map" json:"json_field_map"`
+	// The field order for the key=value based formats, i.e. text, logfmt and
+	// console: the leading keys, the other keys and the trailing keys. The
+	// default is time, level, comp, file, func, ..., msg; a nil list stands
+	// for the default, an empty one means no leading/trailing keys:
+	FieldOrderLeading  []string `yaml:"field_order_leading" json:"field_order_leading"`
+	FieldOrderTrailing []string `yaml:"field_order_trailing" json:"field_order_trailing"`
+	// Whether to leave the other keys unsorted, rather than alphabetically
+	// sorted. Since logrus fields are maps, their insertion order is not
+	// preserved, i.e. the order is unspecified, but sorting is avoided:
+	FieldOrderUnsorted bool `yaml:"field_order_unsorted" json:"field_order_unsorted"`
 	// Log level name: info, warn, ...:
-	Level string `yaml:"level"`
+	Level string `yaml:"level" json:"level"`
 	// Whether to disable the reporting of the source file:line# info:
-	DisableSrcFile bool `yaml:"disable_src_file"`
-	// Whether to log to a file or, if empty, to stderr:
-	LogFile string `yaml:"log_file"`
+	DisableSrcFile bool `yaml:"disable_src_file" json:"disable_src_file"`
+	// Whether to prefix the source file w/ the module path, e.g.
+	// github.com/org/mod/pkg/file.go rather than pkg/file.go, for the files
+	// whose module root was detected, see SetAutoModuleRoot and
+	// AddModulePathPrefix:
+	SrcFileModulePath bool `yaml:"src_file_module_path" json:"src_file_module_path"`
+	// Whether to keep the version in the module path above, if known, e.g.
+	// github.com/org/mod@v1.2.3/pkg/file.go, for the files from the module
+	// cache or from -trimpath builds:
+	SrcFileModuleVersion bool `yaml:"src_file_module_version" json:"src_file_module_version"`
+	// How to log the calling function name, along w/ the source file, e.g.
+	// for github.com/org/mod/pkg.(*Type).Method: off (the default), short
+	// (pkg.(*Type).Method), method ((*Type).Method) or full (the import path
+	// qualified name):
+	FuncName string `yaml:"func_name" json:"func_name"`
+	// The max number of callers whose prettyfied file:line# and function are
+	// cached, use 0 for unbounded. The least recently used ones are evicted,
+	// approximately:
+	CallerCacheMaxSize int `yaml:"caller_cache_max_size" json:"caller_cache_max_size"`
+	// Whether to log to a file, to syslog or journald (see the destination
+	// formats in logger_syslog.go and logger_journald.go) or, if empty, to
+	// stderr:
+	LogFile string `yaml:"log_file" json:"log_file"`
 	// Log file max size, in MB, before rotation, use 0 to disable:
-	LogFileMaxSizeMB int `yaml:"log_file_max_size_mb"`
+	LogFileMaxSizeMB int `yaml:"log_file_max_size_mb" json:"log_file_max_size_mb"`
 	// How many older log files to keep upon rotation:
-	LogFileMaxBackupNum int `yaml:"log_file_max_backup_num"`
+	LogFileMaxBackupNum int `yaml:"log_file_max_backup_num" json:"log_file_max_backup_num"`
+	// Time based rotation interval: hourly, daily or a duration, e.g. 6h;
+	// combinable w/ the max size. Leave empty to disable:
+	LogFileRotateInterval string `yaml:"log_file_rotate_interval" json:"log_file_rotate_interval"`
+	// Whether to use UTC or local time for time based rotation:
+	LogFileRotateUTC bool `yaml:"log_file_rotate_utc" json:"log_file_rotate_utc"`
+	// How many days to keep older log files upon rotation, use 0 to keep them
+	// regardless of age:
+	LogFileMaxAgeDays int `yaml:"log_file_max_age_days" json:"log_file_max_age_days"`
+	// Whether to gzip older log files upon rotation:
+	LogFileCompress bool `yaml:"log_file_compress" json:"log_file_compress"`
+	// Whether to use local time rather than UTC for the timestamps in the
+	// older log file names, for size based rotation only (time based rotation
+	// uses LogFileRotateUTC):
+	LogFileLocalTime bool `yaml:"log_file_local_time" json:"log_file_local_time"`
+	// Whether the log file is rotated by an external tool, e.g. logrotate w/
+	// create/move semantics. The file is opened in append mode, it is never
+	// rotated by the logger, i.e. all the above rotation settings are
+	// ignored, and it is reopened upon SIGUSR1/SIGHUP or ReopenLogFile:
+	LogFileExternalRotate bool `yaml:"log_file_external_rotate" json:"log_file_external_rotate"`
+	// Whether to write the records asynchronously, via a bounded queue per
+	// output, see Flush and Close:
+	Async bool `yaml:"async" json:"async"`
+	// The max number of records in the queue:
+	AsyncQueueSize int `yaml:"async_queue_size" json:"async_queue_size"`
+	// What to do when the queue is full: block, drop_newest, drop_oldest or
+	// drop_below_level:
+	AsyncOverflowPolicy string `yaml:"async_overflow_policy" json:"async_overflow_policy"`
+	// For drop_below_level, the records less severe than this level are
+	// dropped when the queue is full, the others wait for room:
+	AsyncDropBelowLevel string `yaml:"async_drop_below_level" json:"async_drop_below_level"`
+	// How often to flush the buffered records, as a duration:
+	AsyncFlushInterval string `yaml:"async_flush_interval" json:"async_flush_interval"`
+	// Component name to level name, for the component loggers that should
+	// not follow the root level:
+	CompLevels map[string]string `yaml:"comp_levels" json:"comp_levels"`
+	// Multiple simultaneous outputs, each w/ its own level threshold, format
+	// and rotation settings. If not empty, it supersedes the single output
+	// settings above (UseJson, Format, Timestamp*, JsonField*, FieldOrder*
+	// and LogFile*). The 1st output is the primary one, see SetOutput:
+	Outputs []LoggerOutputConfig `yaml:"outputs" json:"outputs"`
+}
+
+func (cfg *LoggerConfig) clone() *LoggerConfig {
+	cfgCopy := *cfg
+	cfgCopy.CompLevels = cloneStringMap(cfg.CompLevels)
+	cfgCopy.JsonFieldMap = cloneStringMap(cfg.JsonFieldMap)
+	cfgCopy.FieldOrderLeading = cloneStringSlice(cfg.FieldOrderLeading)
+	cfgCopy.FieldOrderTrailing = cloneStringSlice(cfg.FieldOrderTrailing)
+	if cfg.Outputs != nil {
+		cfgCopy.Outputs = make([]LoggerOutputConfig, len(cfg.Outputs))
+		copy(cfgCopy.Outputs, cfg.Outputs)
+		for i := range cfgCopy.Outputs {
+			cfgCopy.Outputs[i].JsonFieldMap = cloneStringMap(cfg.Outputs[i].JsonFieldMap)
+			cfgCopy.Outputs[i].FieldOrderLeading = cloneStringSlice(cfg.Outputs[i].FieldOrderLeading)
+			cfgCopy.Outputs[i].FieldOrderTrailing = cloneStringSlice(cfg.Outputs[i].FieldOrderTrailing)
+		}
+	}
+	return &cfgCopy
+}
+
+// Copy a map, nil if nil:
+func cloneStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	mCopy := make(map[string]string, len(m))
+	for k, v := range m {
+		mCopy[k] = v
+	}
+	return mCopy
+}
+
+// Copy a slice, nil if nil, since for some lists nil stands for the default
+// and empty for none:
+func cloneStringSlice(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	sCopy := make([]string, len(s))
+	copy(sCopy, s)
+	return sCopy
 }
 
 func DefaultLoggerConfig() *LoggerConfig {
+	fieldOrderLeading, fieldOrderTrailing := GetLoggerDefaultFieldOrder()
 	return &LoggerConfig{
-		UseJson:             LOGGER_CONFIG_USE_JSON_DEFAULT,
-		Level:               LOGGER_CONFIG_LEVEL_DEFAULT,
-		DisableSrcFile:      LOGGER_CONFIG_DISBALE_SRC_FILE_DEFAULT,
-		LogFile:             LOGGER_CONFIG_LOG_FILE_DEFAULT,
-		LogFileMaxSizeMB:    LOGGER_CONFIG_LOG_FILE_MAX_SIZE_MB_DEFAULT,
-		LogFileMaxBackupNum: LOGGER_CONFIG_LOG_FILE_MAX_BACKUP_NUM_DEFAULT,
+		UseJson:               LOGGER_CONFIG_USE_JSON_DEFAULT,
+		Format:                LOGGER_CONFIG_FORMAT_DEFAULT,
+		TimestampFormat:       LOGGER_CONFIG_TIMESTAMP_FORMAT_DEFAULT,
+		TimestampUTC:          LOGGER_CONFIG_TIMESTAMP_UTC_DEFAULT,
+		DisableTimestamp:      LOGGER_CONFIG_DISABLE_TIMESTAMP_DEFAULT,
+		JsonFieldPreset:       LOGGER_CONFIG_JSON_FIELD_PRESET_DEFAULT,
+		FieldOrderUnsorted:    LOGGER_CONFIG_FIELD_ORDER_UNSORTED_DEFAULT,
+		FieldOrderLeading:     fieldOrderLeading,
+		FieldOrderTrailing:    fieldOrderTrailing,
+		Level:                 LOGGER_CONFIG_LEVEL_DEFAULT,
+		DisableSrcFile:        LOGGER_CONFIG_DISBALE_SRC_FILE_DEFAULT,
+		SrcFileModulePath:     LOGGER_CONFIG_SRC_FILE_MODULE_PATH_DEFAULT,
+		SrcFileModuleVersion:  LOGGER_CONFIG_SRC_FILE_MODULE_VERSION_DEFAULT,
+		FuncName:              LOGGER_CONFIG_FUNC_NAME_DEFAULT,
+		CallerCacheMaxSize:    LOGGER_CONFIG_CALLER_CACHE_MAX_SIZE_DEFAULT,
+		LogFile:               LOGGER_CONFIG_LOG_FILE_DEFAULT,
+		LogFileMaxSizeMB:      LOGGER_CONFIG_LOG_FILE_MAX_SIZE_MB_DEFAULT,
+		LogFileMaxBackupNum:   LOGGER_CONFIG_LOG_FILE_MAX_BACKUP_NUM_DEFAULT,
+		LogFileRotateInterval: LOGGER_CONFIG_LOG_FILE_ROTATE_INTERVAL_DEFAULT,
+		LogFileRotateUTC:      LOGGER_CONFIG_LOG_FILE_ROTATE_UTC_DEFAULT,
+		LogFileMaxAgeDays:     LOGGER_CONFIG_LOG_FILE_MAX_AGE_DAYS_DEFAULT,
+		LogFileCompress:       LOGGER_CONFIG_LOG_FILE_COMPRESS_DEFAULT,
+		LogFileLocalTime:      LOGGER_CONFIG_LOG_FILE_LOCAL_TIME_DEFAULT,
+		LogFileExternalRotate: LOGGER_CONFIG_LOG_FILE_EXTERNAL_ROTATE_DEFAULT,
+		Async:                 LOGGER_CONFIG_ASYNC_DEFAULT,
+		AsyncQueueSize:        LOGGER_CONFIG_ASYNC_QUEUE_SIZE_DEFAULT,
+		AsyncOverflowPolicy:   LOGGER_CONFIG_ASYNC_OVERFLOW_POLICY_DEFAULT,
+		AsyncDropBelowLevel:   LOGGER_CONFIG_ASYNC_DROP_BELOW_LEVEL_DEFAULT,
+		AsyncFlushInterval:    LOGGER_CONFIG_ASYNC_FLUSH_INTERVAL_DEFAULT,
 	}
 }
 
 func NewCollectableLogger() *CollectableLogger {
 	prettyfier := logrusx_internal.NewCallerPrettyfier()
+	out := &syncWriter{out: os.Stderr}
+	callerHelpers := &callerHelpers{}
+	outputsFormatter := &outputsFormatter{callerHelpers: callerHelpers}
+	outputsFormatter.setOutputs([]*loggerOutput{
+		{
+			out:       out,
+			formatter: logrusx_internal.NewTextFormatter(prettyfier.Pretiffy, nil, nil),
+			level:     logrus.TraceLevel,
+		},
+	})
 	return &CollectableLogger{
 		Logger: logrus.Logger{
-			Out: os.Stderr,
-			//Hooks:        make(logrus.LevelHooks),
-			Formatter:    logrusx_internal.NewTextFormatter(prettyfier),
+			Out:          out,
+			Hooks:        make(logrus.LevelHooks),
+			Formatter:    outputsFormatter,
 			Level:        LOGGER_DEFAULT_LEVEL,
 			ReportCaller: true,
+			ExitFunc:     os.Exit,
 		},
-		prettyfier: prettyfier,
+		prettyfier:       prettyfier,
+		callerHelpers:    callerHelpers,
+		out:              out,
+		outputsFormatter: outputsFormatter,
+		comps:            make(map[string]*compLogger),
+		compLevels:       make(map[string]logrus.Level),
 	}
 }
 
 // Set the logger based on config, post creation. This may be necessary since an
 // app may start with the default logger and later, after loading loading a
 // configuration and/or parsing the command line args, it may need to amend the logger.
+//
+// The config is validated and all the settings are prepared before any of them
+// is applied, such that in case of error the logger is left unchanged. The log
+// files are opened last, after all the outputs were built; should opening one
+// of them fail, the ones opened before it are closed, but they may have been
+// rotated already.
 func (logger *CollectableLogger) SetLogger(cfg *LoggerConfig) error {
 	if cfg == nil {
 		cfg = DefaultLoggerConfig()
 	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	logger.cfgMu.Lock()
+	defer logger.cfgMu.Unlock()
 
 	levelName := cfg.Level
+	level := logger.Logger.GetLevel()
 	if levelName != "" {
-		level, err := logrus.ParseLevel(levelName)
+		var err error
+		level, err = logrus.ParseLevel(levelName)
 		if err != nil {
 			return err
 		}
-		logger.SetLevel(level)
 	}
 
-	if cfg.UseJson {
-		logger.SetFormatter(logrusx_internal.NewJsonFormatter(logger.prettyfier))
-	} else {
-		logger.SetFormatter(logrusx_internal.NewTextFormatter(logger.prettyfier))
+	compLevels := make(map[string]logrus.Level, len(cfg.CompLevels))
+	for compName, levelName := range cfg.CompLevels {
+		compLevel, err := logrus.ParseLevel(levelName)
+		if err != nil {
+			return err
+		}
+		compLevels[compName] = compLevel
 	}
 
-	logger.SetReportCaller(!cfg.DisableSrcFile)
+	outputs, primaryOut, unusedLogFiles, err := logger.newOutputs(cfg)
+	if err != nil {
+		return err
+	}
 
-	switch logFile := cfg.LogFile; logFile {
-	case "stderr":
-		logger.SetOutput(os.Stderr)
-	case "stdout":
-		logger.SetOutput(os.Stdout)
-	case "":
-	default:
-		// Create log dir as needed:
-		logDir := path.Dir(cfg.LogFile)
-		_, err := os.Stat(logDir)
-		if err != nil {
-			err = os.MkdirAll(logDir, os.ModePerm)
-			if err != nil {
-				return err
-			}
-		}
-		// Check if the log file exists, in which case force rotate it before
-		// the 1st use:
-		_, err = os.Stat(cfg.LogFile)
-		forceRotate := err == nil
-		logFile := &lumberjack.Logger{
-			Filename:   cfg.LogFile,
-			MaxSize:    cfg.LogFileMaxSizeMB,
-			MaxBackups: cfg.LogFileMaxBackupNum,
-		}
-		if forceRotate {
-			err := logFile.Rotate()
-			if err != nil {
-				return err
-			}
-		}
-		logger.SetOutput(logFile)
+	logger.SetLevel(level)
+	logger.setCompLevels(compLevels)
+	logger.SetReportCaller(!cfg.DisableSrcFile)
+	logger.prettyfier.SetWithModulePath(cfg.SrcFileModulePath)
+	logger.prettyfier.SetWithModuleVersion(cfg.SrcFileModuleVersion)
+	logger.prettyfier.SetFuncNameMode(cfg.FuncName)
+	logger.prettyfier.SetCacheMaxSize(cfg.CallerCacheMaxSize)
+	// The async writers are replaced every time, drain the current ones
+	// before switching the primary output and closing the log files:
+	if err := logger.closeAsyncWriters(logger.outputsFormatter.getOutputs()); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush log, %v\n", err)
+	}
+	if primaryOut != nil {
+		logger.SetOutput(primaryOut)
 	}
+	logger.outputsFormatter.setOutputs(outputs)
+	for _, logFile := range unusedLogFiles {
+		logFile.Close()
+	}
+	logger.updateReopenOnSignal()
 
+	logger.cfg = cfg.clone()
 	return nil
 }
 
-func (logger *CollectableLogger) NewCompLogger(compName string) *logrus.Entry {
-	return logger.WithField(logrusx_internal.LOGGER_COMPONENT_FIELD_NAME, compName)
+// Get a copy of the config most recently applied via SetLogger, nil if none:
+func (logger *CollectableLogger) GetLoggerConfig() *LoggerConfig {
+	logger.cfgMu.Lock()
+	defer logger.cfgMu.Unlock()
+	if logger.cfg == nil {
+		return nil
+	}
+	return logger.cfg.clone()
 }
 
 // Add the prefix based on the caller's stack, going back `upNDirs` directories
@@ -175,10 +483,30 @@ func (logger *CollectableLogger) AddCallerSrcPathPrefix(upNDirs int) error {
 	return logger.prettyfier.AddCallerSrcPathPrefix(upNDirs, 1)
 }
 
+// Add a module path, e.g. github.com/org/mod, whose files should be logged
+// relative to the module root. Unlike AddCallerSrcPathPrefix, it works for
+// -trimpath builds too, where the file paths are module qualified, e.g.
+// github.com/org/mod@v1.2.3/pkg/file.go. It is needed only for the modules
+// not covered by the automatic detection, see SetAutoModuleRoot.
+func (logger *CollectableLogger) AddModulePathPrefix(modulePath string) {
+	logger.prettyfier.AddModulePath(modulePath)
+}
+
+// Enable/disable the automatic detection of the module root, used for the
+// files not matching any of the prefixes added via AddCallerSrcPathPrefix or
+// AddModulePathPrefix. The module is determined from the caller's file path,
+// if module qualified, or from its package path, based on the build info, or,
+// failing that, by locating go.mod for the caller's file. It is
+// enabled by default, which removes the need for AddCallerSrcPathPrefix in
+// most cases.
+func (logger *CollectableLogger) SetAutoModuleRoot(autoModuleRoot bool) {
+	logger.prettyfier.SetAutoModuleRoot(autoModuleRoot)
+}
+
 // Set how many sub-dirs to keep, starting from the filename towards the root,
-// in case there is no prefix match. (the fallback, that is). For instance if
-// the caller's path is /a/b/c/f.go and n == 2, the source will be logged as
-// b/c/f.go. The builtin default is 1.
+// in case there is no prefix match nor a detected module root (the fallback,
+// that is). For instance if the caller's path is /a/b/c/f.go and n == 2, the
+// source will be logged as b/c/f.go. The builtin default is 1.
 func (logger *CollectableLogger) SetKeepNDirs(n int) {
 	logger.prettyfier.SetKeepNDirs(n)
 }
@@ -0,0 +1,195 @@
+// HTTP admin handler for inspecting and changing the log levels at runtime
+
+package logrusx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The state reported by the handler:
+type LoggerAdminState struct {
+	// The effective config, i.e. the one most recently applied, amended with
+	// the runtime changes to the root and component levels:
+	Config *LoggerConfig `json:"config"`
+	// The component loggers:
+	Comps []*CompInfo `json:"comps"`
+}
+
+// The request for changing a level:
+type LoggerAdminLevelRequest struct {
+	// The component name, leave empty for the root logger:
+	Comp string `json:"comp"`
+	// The level name; for a component, an empty level will revert it to the
+	// root level:
+	Level string `json:"level"`
+	// Optional, Go duration format, e.g. "10m", after which the level reverts
+	// to the one in effect before this request or, if it supersedes a pending
+	// TTL request, before the latter:
+	TTL string `json:"ttl"`
+}
+
+// Serve GET for the current state and PUT/POST w/ a LoggerAdminLevelRequest
+// JSON body for changing a level; the latter will return the updated state.
+type LoggerAdminHandler struct {
+	logger *CollectableLogger
+	// Pending reverts, by component name, "" for root:
+	m       sync.Mutex
+	reverts map[string]*loggerAdminRevert
+}
+
+// A pending revert: the timer and the function restoring the state prior to
+// the 1st of the chained TTL requests:
+type loggerAdminRevert struct {
+	timer  *time.Timer
+	revert func()
+}
+
+func NewLoggerAdminHandler(logger *CollectableLogger) *LoggerAdminHandler {
+	return &LoggerAdminHandler{
+		logger:  logger,
+		reverts: make(map[string]*loggerAdminRevert),
+	}
+}
+
+// Get the effective config, i.e. the one most recently applied via SetLogger
+// (or the one matching NewCollectableLogger), amended with the runtime changes
+// to the root and component levels.
+func (logger *CollectableLogger) GetEffectiveLoggerConfig() *LoggerConfig {
+	cfg := logger.GetLoggerConfig()
+	if cfg == nil {
+		// Never configured, i.e. text to stderr, unless replaced via
+		// SetOutput:
+		cfg = DefaultLoggerConfig()
+		cfg.UseJson = false
+		cfg.Format = LOGGER_FORMAT_TEXT
+		switch logger.GetOutput() {
+		case os.Stderr:
+			cfg.LogFile = "stderr"
+		case os.Stdout:
+			cfg.LogFile = "stdout"
+		}
+	}
+	logger.compMu.Lock()
+	defer logger.compMu.Unlock()
+	cfg.Level = logger.Logger.GetLevel().String()
+	cfg.CompLevels = make(map[string]string, len(logger.compLevels))
+	for compName, level := range logger.compLevels {
+		cfg.CompLevels[compName] = level.String()
+	}
+	return cfg
+}
+
+func (h *LoggerAdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		req := &LoggerAdminLevelRequest{}
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := h.applyLevelRequest(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state := &LoggerAdminState{
+		Config: h.logger.GetEffectiveLoggerConfig(),
+		Comps:  h.logger.GetCompInfoList(),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(state)
+}
+
+func (h *LoggerAdminHandler) applyLevelRequest(req *LoggerAdminLevelRequest) error {
+	var (
+		level    logrus.Level
+		ttl      time.Duration
+		err      error
+		setLevel = req.Level != ""
+	)
+	if setLevel {
+		level, err = logrus.ParseLevel(req.Level)
+		if err != nil {
+			return fmt.Errorf("invalid level %q, must be one of %v", req.Level, GetLogLevelNames())
+		}
+	} else if req.Comp == "" {
+		return fmt.Errorf("missing level")
+	}
+	if req.TTL != "" {
+		ttl, err = time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("invalid ttl %q", req.TTL)
+		}
+	}
+
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	// A new request supersedes any pending revert for the same target. W/ a
+	// TTL, the revert is to the level in effect before the pending one, i.e.
+	// the one prior to all the temporary changes, otherwise the change is
+	// permanent:
+	revert := h.setLevel(req.Comp, level, setLevel)
+	if pending := h.reverts[req.Comp]; pending != nil {
+		pending.timer.Stop()
+		delete(h.reverts, req.Comp)
+		revert = pending.revert
+	}
+	if ttl > 0 {
+		pending := &loggerAdminRevert{revert: revert}
+		pending.timer = time.AfterFunc(ttl, func() {
+			h.m.Lock()
+			defer h.m.Unlock()
+			if h.reverts[req.Comp] == pending {
+				pending.revert()
+				delete(h.reverts, req.Comp)
+			}
+		})
+		h.reverts[req.Comp] = pending
+	}
+	return nil
+}
+
+// Set/reset the level and return the function that restores the previous
+// state:
+func (h *LoggerAdminHandler) setLevel(compName string, level logrus.Level, setLevel bool) func() {
+	logger := h.logger
+	if compName == "" {
+		prevLevel := logger.Logger.GetLevel()
+		logger.SetLevel(level)
+		return func() { logger.SetLevel(prevLevel) }
+	}
+
+	logger.compMu.Lock()
+	prevLevel, hadLevel := logger.compLevels[compName]
+	logger.compMu.Unlock()
+	if setLevel {
+		logger.SetCompLevel(compName, level)
+	} else {
+		logger.ResetCompLevel(compName)
+	}
+	return func() {
+		if hadLevel {
+			logger.SetCompLevel(compName, prevLevel)
+		} else {
+			logger.ResetCompLevel(compName)
+		}
+	}
+}
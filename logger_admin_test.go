@@ -0,0 +1,116 @@
+package logrusx_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bgp59/logrusx"
+	"github.com/sirupsen/logrus"
+)
+
+func doAdminRequest(t *testing.T, h http.Handler, method string, body string) (int, *logrusx.LoggerAdminState) {
+	req := httptest.NewRequest(method, "/log", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		return rec.Code, nil
+	}
+	state := &logrusx.LoggerAdminState{}
+	if err := json.Unmarshal(rec.Body.Bytes(), state); err != nil {
+		t.Fatalf("%s %q: %v", method, body, err)
+	}
+	return rec.Code, state
+}
+
+func TestLoggerAdminHandlerUnconfigured(t *testing.T) {
+	h := logrusx.NewLoggerAdminHandler(logrusx.NewCollectableLogger())
+	code, state := doAdminRequest(t, h, http.MethodGet, "")
+	if code != http.StatusOK {
+		t.Fatalf("GET: want %d, got %d", http.StatusOK, code)
+	}
+	if state.Config.UseJson || state.Config.Format != logrusx.LOGGER_FORMAT_TEXT || state.Config.LogFile != "stderr" {
+		t.Errorf("GET: want text to stderr, got %#v", state.Config)
+	}
+}
+
+func TestLoggerAdminHandler(t *testing.T) {
+	logger := logrusx.NewCollectableLogger()
+	err := logger.SetLogger(&logrusx.LoggerConfig{
+		Level:      "info",
+		CompLevels: map[string]string{"db": "warn"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.NewCompLogger("db")
+	logger.NewCompLogger("http")
+	h := logrusx.NewLoggerAdminHandler(logger)
+
+	code, state := doAdminRequest(t, h, http.MethodGet, "")
+	if code != http.StatusOK {
+		t.Fatalf("GET: want %d, got %d", http.StatusOK, code)
+	}
+	if state.Config.Level != "info" || state.Config.CompLevels["db"] != "warning" || len(state.Comps) != 2 {
+		t.Fatalf("GET: unexpected %#v", state)
+	}
+
+	for _, tc := range []struct {
+		method   string
+		body     string
+		wantCode int
+	}{
+		{http.MethodDelete, "", http.StatusMethodNotAllowed},
+		{http.MethodPut, `{"level": "loud"}`, http.StatusBadRequest},
+		{http.MethodPut, `{"level": "debug", "ttl": "soon"}`, http.StatusBadRequest},
+		{http.MethodPut, `{"level": "debug", "extra": 1}`, http.StatusBadRequest},
+		{http.MethodPut, `{}`, http.StatusBadRequest},
+	} {
+		if code, _ := doAdminRequest(t, h, tc.method, tc.body); code != tc.wantCode {
+			t.Errorf("%s %q: want %d, got %d", tc.method, tc.body, tc.wantCode, code)
+		}
+	}
+
+	_, state = doAdminRequest(t, h, http.MethodPut, `{"level": "error"}`)
+	if state.Config.Level != "error" || logger.GetCompLevel("http") != logrus.ErrorLevel {
+		t.Fatalf("PUT root: unexpected %#v", state.Config)
+	}
+
+	_, state = doAdminRequest(t, h, http.MethodPost, `{"comp": "db"}`)
+	if _, ok := state.Config.CompLevels["db"]; ok || logger.GetCompLevel("db") != logrus.ErrorLevel {
+		t.Fatalf("POST reset db: unexpected %#v", state.Config)
+	}
+
+	_, state = doAdminRequest(t, h, http.MethodPost, `{"comp": "http", "level": "trace", "ttl": "20ms"}`)
+	if state.Config.CompLevels["http"] != "trace" {
+		t.Fatalf("POST http w/ ttl: unexpected %#v", state.Config)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for logger.GetCompLevel("http") != logrus.ErrorLevel {
+		if time.Now().After(deadline) {
+			t.Fatalf("http level: want %v, got %v", logrus.ErrorLevel, logger.GetCompLevel("http"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, state = doAdminRequest(t, h, http.MethodGet, "")
+	if _, ok := state.Config.CompLevels["http"]; ok {
+		t.Fatalf("GET after revert: unexpected %#v", state.Config)
+	}
+
+	// Chained TTL requests revert to the level prior to the 1st one:
+	doAdminRequest(t, h, http.MethodPut, `{"level": "debug", "ttl": "1h"}`)
+	_, state = doAdminRequest(t, h, http.MethodPut, `{"level": "trace", "ttl": "20ms"}`)
+	if state.Config.Level != "trace" {
+		t.Fatalf("PUT root w/ chained ttl: unexpected %#v", state.Config)
+	}
+	deadline = time.Now().Add(2 * time.Second)
+	for logger.Logger.GetLevel() != logrus.ErrorLevel {
+		if time.Now().After(deadline) {
+			t.Fatalf("root level: want %v, got %v", logrus.ErrorLevel, logger.Logger.GetLevel())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
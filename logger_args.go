@@ -5,83 +5,365 @@ package logrusx
 import (
 	"flag"
 	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
 )
 
-var loggerFlags = make(map[string]any)
+// The flag set operations needed for the logger args; flag.FlagSet and
+// pflag.FlagSet are supported via adapters:
+type loggerArgsFlagSet interface {
+	Bool(name string, value bool, usage string) *bool
+	String(name string, value string, usage string) *string
+	Int(name string, value int, usage string) *int
+	// Invoke fn for each flag that was set on the command line:
+	visitSet(fn func(name string))
+}
 
-func EnableLoggerArgs() {
-	loggerFlags[LOGGER_ARGS_USE_JSON] = flag.Bool(
-		LOGGER_ARGS_USE_JSON,
+type stdFlagSet struct {
+	*flag.FlagSet
+}
+
+func (fs stdFlagSet) visitSet(fn func(name string)) {
+	fs.Visit(func(f *flag.Flag) { fn(f.Name) })
+}
+
+type pflagFlagSet struct {
+	*pflag.FlagSet
+}
+
+func (fs pflagFlagSet) visitSet(fn func(name string)) {
+	fs.Visit(func(f *pflag.Flag) { fn(f.Name) })
+}
+
+// Logger args registered on a specific flag set, w/ an optional prefix for the
+// names, e.g. for prefix "svc" the level arg will be "svc-log-level".
+type LoggerArgs struct {
+	fs     loggerArgsFlagSet
+	prefix string
+	// The flag value pointers, by arg name (w/o prefix):
+	flags map[string]any
+}
+
+// Register the logger args on a flag.FlagSet:
+func NewLoggerArgs(fs *flag.FlagSet, prefix string) *LoggerArgs {
+	return newLoggerArgs(stdFlagSet{fs}, prefix)
+}
+
+// Register the logger args on a pflag.FlagSet, e.g. for cobra based CLIs:
+func NewLoggerPFlagArgs(fs *pflag.FlagSet, prefix string) *LoggerArgs {
+	return newLoggerArgs(pflagFlagSet{fs}, prefix)
+}
+
+func newLoggerArgs(fs loggerArgsFlagSet, prefix string) *LoggerArgs {
+	if prefix != "" && !strings.HasSuffix(prefix, "-") {
+		prefix += "-"
+	}
+	args := &LoggerArgs{
+		fs:     fs,
+		prefix: prefix,
+		flags:  make(map[string]any),
+	}
+
+	args.flags[LOGGER_ARGS_USE_JSON] = fs.Bool(
+		prefix+LOGGER_ARGS_USE_JSON,
 		LOGGER_CONFIG_USE_JSON_DEFAULT,
 		"Structure the logged record in JSON",
 	)
 
-	loggerFlags[LOGGER_ARGS_LEVEL] = flag.String(
-		LOGGER_ARGS_LEVEL,
+	args.flags[LOGGER_ARGS_FORMAT] = fs.String(
+		prefix+LOGGER_ARGS_FORMAT,
+		LOGGER_CONFIG_FORMAT_DEFAULT,
+		fmt.Sprintf(
+			"Log record format, one of %v; if set, it supersedes -%s%s",
+			GetLoggerFormatNames(), prefix, LOGGER_ARGS_USE_JSON,
+		),
+	)
+
+	args.flags[LOGGER_ARGS_TIMESTAMP_FORMAT] = fs.String(
+		prefix+LOGGER_ARGS_TIMESTAMP_FORMAT,
+		LOGGER_CONFIG_TIMESTAMP_FORMAT_DEFAULT,
+		fmt.Sprintf("Log timestamp format, one of %v or a Go time layout", loggerTimestampFormats),
+	)
+
+	args.flags[LOGGER_ARGS_TIMESTAMP_UTC] = fs.Bool(
+		prefix+LOGGER_ARGS_TIMESTAMP_UTC,
+		LOGGER_CONFIG_TIMESTAMP_UTC_DEFAULT,
+		"Use UTC rather than local time for the log timestamp",
+	)
+
+	args.flags[LOGGER_ARGS_DISABLE_TIMESTAMP] = fs.Bool(
+		prefix+LOGGER_ARGS_DISABLE_TIMESTAMP,
+		LOGGER_CONFIG_DISABLE_TIMESTAMP_DEFAULT,
+		"Disable the log timestamp, e.g. for outputs adding their own",
+	)
+
+	args.flags[LOGGER_ARGS_JSON_FIELD_PRESET] = fs.String(
+		prefix+LOGGER_ARGS_JSON_FIELD_PRESET,
+		LOGGER_CONFIG_JSON_FIELD_PRESET_DEFAULT,
+		fmt.Sprintf("JSON field keys preset, one of %v; leave empty for the logrus keys", GetLoggerJsonFieldPresetNames()),
+	)
+
+	defaultLeading, defaultTrailing := GetLoggerDefaultFieldOrder()
+	args.flags[LOGGER_ARGS_FIELD_ORDER_LEADING] = fs.String(
+		prefix+LOGGER_ARGS_FIELD_ORDER_LEADING,
+		strings.Join(defaultLeading, ","),
+		"Comma separated list of leading field keys, for key=value based formats",
+	)
+
+	args.flags[LOGGER_ARGS_FIELD_ORDER_TRAILING] = fs.String(
+		prefix+LOGGER_ARGS_FIELD_ORDER_TRAILING,
+		strings.Join(defaultTrailing, ","),
+		"Comma separated list of trailing field keys, for key=value based formats",
+	)
+
+	args.flags[LOGGER_ARGS_FIELD_ORDER_UNSORTED] = fs.Bool(
+		prefix+LOGGER_ARGS_FIELD_ORDER_UNSORTED,
+		LOGGER_CONFIG_FIELD_ORDER_UNSORTED_DEFAULT,
+		"Leave the fields other than the leading and trailing ones unsorted",
+	)
+
+	args.flags[LOGGER_ARGS_LEVEL] = fs.String(
+		prefix+LOGGER_ARGS_LEVEL,
 		LOGGER_CONFIG_LEVEL_DEFAULT,
 		fmt.Sprintf("Log level name, one of %v", GetLogLevelNames()),
 	)
 
-	loggerFlags[LOGGER_ARGS_DISABALE_SRC_FILE] = flag.Bool(
-		LOGGER_ARGS_DISABALE_SRC_FILE,
+	args.flags[LOGGER_ARGS_DISABALE_SRC_FILE] = fs.Bool(
+		prefix+LOGGER_ARGS_DISABALE_SRC_FILE,
 		LOGGER_CONFIG_DISBALE_SRC_FILE_DEFAULT,
 		"Disable the reporting of the source file:line# info",
 	)
 
-	loggerFlags[LOGGER_ARGS_LOG_FILE] = flag.String(
-		LOGGER_ARGS_LOG_FILE,
+	args.flags[LOGGER_ARGS_SRC_FILE_MODULE_PATH] = fs.Bool(
+		prefix+LOGGER_ARGS_SRC_FILE_MODULE_PATH,
+		LOGGER_CONFIG_SRC_FILE_MODULE_PATH_DEFAULT,
+		"Prefix the source file w/ its module path",
+	)
+
+	args.flags[LOGGER_ARGS_SRC_FILE_MODULE_VERSION] = fs.Bool(
+		prefix+LOGGER_ARGS_SRC_FILE_MODULE_VERSION,
+		LOGGER_CONFIG_SRC_FILE_MODULE_VERSION_DEFAULT,
+		"Keep the version in the module path of the source file, if known",
+	)
+
+	args.flags[LOGGER_ARGS_FUNC_NAME] = fs.String(
+		prefix+LOGGER_ARGS_FUNC_NAME,
+		LOGGER_CONFIG_FUNC_NAME_DEFAULT,
+		fmt.Sprintf("How to log the calling function name, one of %v", loggerFuncNameModes),
+	)
+
+	args.flags[LOGGER_ARGS_CALLER_CACHE_MAX_SIZE] = fs.Int(
+		prefix+LOGGER_ARGS_CALLER_CACHE_MAX_SIZE,
+		LOGGER_CONFIG_CALLER_CACHE_MAX_SIZE_DEFAULT,
+		"The max number of cached callers, use 0 for unbounded",
+	)
+
+	args.flags[LOGGER_ARGS_LOG_FILE] = fs.String(
+		prefix+LOGGER_ARGS_LOG_FILE,
 		LOGGER_CONFIG_LOG_FILE_DEFAULT,
 		"Log to a file or use stdout/stderr",
 	)
 
-	loggerFlags[LOGGER_ARGS_LOG_FILE_MAX_SIZE_MB] = flag.Int(
-		LOGGER_ARGS_LOG_FILE_MAX_SIZE_MB,
+	args.flags[LOGGER_ARGS_LOG_FILE_MAX_SIZE_MB] = fs.Int(
+		prefix+LOGGER_ARGS_LOG_FILE_MAX_SIZE_MB,
 		LOGGER_CONFIG_LOG_FILE_MAX_SIZE_MB_DEFAULT,
 		"Log file max size, in MB, before rotation, use 0 to disable",
 	)
 
-	loggerFlags[LOGGER_ARGS_LOG_FILE_MAX_BACKUP_NUM] = flag.Int(
-		LOGGER_ARGS_LOG_FILE_MAX_BACKUP_NUM,
+	args.flags[LOGGER_ARGS_LOG_FILE_MAX_BACKUP_NUM] = fs.Int(
+		prefix+LOGGER_ARGS_LOG_FILE_MAX_BACKUP_NUM,
 		LOGGER_CONFIG_LOG_FILE_MAX_BACKUP_NUM_DEFAULT,
 		"How many older log files to keep upon rotation",
 	)
+
+	args.flags[LOGGER_ARGS_LOG_FILE_ROTATE_INTERVAL] = fs.String(
+		prefix+LOGGER_ARGS_LOG_FILE_ROTATE_INTERVAL,
+		LOGGER_CONFIG_LOG_FILE_ROTATE_INTERVAL_DEFAULT,
+		fmt.Sprintf(
+			"Log file time based rotation interval: %s, %s or a duration, e.g. 6h, leave empty to disable",
+			LOGGER_ROTATE_INTERVAL_HOURLY, LOGGER_ROTATE_INTERVAL_DAILY,
+		),
+	)
+
+	args.flags[LOGGER_ARGS_LOG_FILE_ROTATE_UTC] = fs.Bool(
+		prefix+LOGGER_ARGS_LOG_FILE_ROTATE_UTC,
+		LOGGER_CONFIG_LOG_FILE_ROTATE_UTC_DEFAULT,
+		"Use UTC rather than local time for time based rotation",
+	)
+
+	args.flags[LOGGER_ARGS_LOG_FILE_MAX_AGE_DAYS] = fs.Int(
+		prefix+LOGGER_ARGS_LOG_FILE_MAX_AGE_DAYS,
+		LOGGER_CONFIG_LOG_FILE_MAX_AGE_DAYS_DEFAULT,
+		"How many days to keep older log files upon rotation, use 0 to disable",
+	)
+
+	args.flags[LOGGER_ARGS_LOG_FILE_COMPRESS] = fs.Bool(
+		prefix+LOGGER_ARGS_LOG_FILE_COMPRESS,
+		LOGGER_CONFIG_LOG_FILE_COMPRESS_DEFAULT,
+		"Gzip older log files upon rotation",
+	)
+
+	args.flags[LOGGER_ARGS_LOG_FILE_LOCAL_TIME] = fs.Bool(
+		prefix+LOGGER_ARGS_LOG_FILE_LOCAL_TIME,
+		LOGGER_CONFIG_LOG_FILE_LOCAL_TIME_DEFAULT,
+		"Use local time rather than UTC for older log file names, size based rotation only",
+	)
+
+	args.flags[LOGGER_ARGS_LOG_FILE_EXTERNAL_ROTATE] = fs.Bool(
+		prefix+LOGGER_ARGS_LOG_FILE_EXTERNAL_ROTATE,
+		LOGGER_CONFIG_LOG_FILE_EXTERNAL_ROTATE_DEFAULT,
+		"The log file is rotated externally, e.g. by logrotate, reopen it upon SIGUSR1/SIGHUP",
+	)
+
+	args.flags[LOGGER_ARGS_ASYNC] = fs.Bool(
+		prefix+LOGGER_ARGS_ASYNC,
+		LOGGER_CONFIG_ASYNC_DEFAULT,
+		"Write the records asynchronously, via a bounded queue",
+	)
+
+	args.flags[LOGGER_ARGS_ASYNC_QUEUE_SIZE] = fs.Int(
+		prefix+LOGGER_ARGS_ASYNC_QUEUE_SIZE,
+		LOGGER_CONFIG_ASYNC_QUEUE_SIZE_DEFAULT,
+		"The max number of records in the async queue",
+	)
+
+	args.flags[LOGGER_ARGS_ASYNC_OVERFLOW_POLICY] = fs.String(
+		prefix+LOGGER_ARGS_ASYNC_OVERFLOW_POLICY,
+		LOGGER_CONFIG_ASYNC_OVERFLOW_POLICY_DEFAULT,
+		fmt.Sprintf("What to do when the async queue is full, one of %v", loggerAsyncOverflowPolicies),
+	)
+
+	args.flags[LOGGER_ARGS_ASYNC_DROP_BELOW_LEVEL] = fs.String(
+		prefix+LOGGER_ARGS_ASYNC_DROP_BELOW_LEVEL,
+		LOGGER_CONFIG_ASYNC_DROP_BELOW_LEVEL_DEFAULT,
+		fmt.Sprintf(
+			"For %s, drop the records less severe than this level when the async queue is full",
+			LOGGER_ASYNC_OVERFLOW_DROP_BELOW_LEVEL,
+		),
+	)
+
+	args.flags[LOGGER_ARGS_ASYNC_FLUSH_INTERVAL] = fs.String(
+		prefix+LOGGER_ARGS_ASYNC_FLUSH_INTERVAL,
+		LOGGER_CONFIG_ASYNC_FLUSH_INTERVAL_DEFAULT,
+		"How often to flush the async buffered records, as a duration",
+	)
+
+	return args
 }
 
-func applyFlag(name string, cfg *LoggerConfig) {
-	if flagPtr, ok := loggerFlags[name]; ok {
+func (args *LoggerArgs) applyFlag(name string, cfg *LoggerConfig) {
+	if flagPtr, ok := args.flags[name]; ok {
 		switch name {
 		case LOGGER_ARGS_USE_JSON:
 			cfg.UseJson = *(flagPtr.(*bool))
+		case LOGGER_ARGS_FORMAT:
+			cfg.Format = *(flagPtr.(*string))
+		case LOGGER_ARGS_TIMESTAMP_FORMAT:
+			cfg.TimestampFormat = *(flagPtr.(*string))
+		case LOGGER_ARGS_TIMESTAMP_UTC:
+			cfg.TimestampUTC = *(flagPtr.(*bool))
+		case LOGGER_ARGS_DISABLE_TIMESTAMP:
+			cfg.DisableTimestamp = *(flagPtr.(*bool))
+		case LOGGER_ARGS_JSON_FIELD_PRESET:
+			cfg.JsonFieldPreset = *(flagPtr.(*string))
+		case LOGGER_ARGS_FIELD_ORDER_LEADING:
+			cfg.FieldOrderLeading = parseFieldKeyList(*(flagPtr.(*string)))
+		case LOGGER_ARGS_FIELD_ORDER_TRAILING:
+			cfg.FieldOrderTrailing = parseFieldKeyList(*(flagPtr.(*string)))
+		case LOGGER_ARGS_FIELD_ORDER_UNSORTED:
+			cfg.FieldOrderUnsorted = *(flagPtr.(*bool))
 		case LOGGER_ARGS_LEVEL:
 			cfg.Level = *(flagPtr.(*string))
 		case LOGGER_ARGS_DISABALE_SRC_FILE:
 			cfg.DisableSrcFile = *(flagPtr.(*bool))
+		case LOGGER_ARGS_SRC_FILE_MODULE_PATH:
+			cfg.SrcFileModulePath = *(flagPtr.(*bool))
+		case LOGGER_ARGS_SRC_FILE_MODULE_VERSION:
+			cfg.SrcFileModuleVersion = *(flagPtr.(*bool))
+		case LOGGER_ARGS_FUNC_NAME:
+			cfg.FuncName = *(flagPtr.(*string))
+		case LOGGER_ARGS_CALLER_CACHE_MAX_SIZE:
+			cfg.CallerCacheMaxSize = *(flagPtr.(*int))
 		case LOGGER_ARGS_LOG_FILE:
 			cfg.LogFile = *(flagPtr.(*string))
 		case LOGGER_ARGS_LOG_FILE_MAX_SIZE_MB:
 			cfg.LogFileMaxSizeMB = *(flagPtr.(*int))
 		case LOGGER_ARGS_LOG_FILE_MAX_BACKUP_NUM:
 			cfg.LogFileMaxBackupNum = *(flagPtr.(*int))
+		case LOGGER_ARGS_LOG_FILE_ROTATE_INTERVAL:
+			cfg.LogFileRotateInterval = *(flagPtr.(*string))
+		case LOGGER_ARGS_LOG_FILE_ROTATE_UTC:
+			cfg.LogFileRotateUTC = *(flagPtr.(*bool))
+		case LOGGER_ARGS_LOG_FILE_MAX_AGE_DAYS:
+			cfg.LogFileMaxAgeDays = *(flagPtr.(*int))
+		case LOGGER_ARGS_LOG_FILE_COMPRESS:
+			cfg.LogFileCompress = *(flagPtr.(*bool))
+		case LOGGER_ARGS_LOG_FILE_LOCAL_TIME:
+			cfg.LogFileLocalTime = *(flagPtr.(*bool))
+		case LOGGER_ARGS_LOG_FILE_EXTERNAL_ROTATE:
+			cfg.LogFileExternalRotate = *(flagPtr.(*bool))
+		case LOGGER_ARGS_ASYNC:
+			cfg.Async = *(flagPtr.(*bool))
+		case LOGGER_ARGS_ASYNC_QUEUE_SIZE:
+			cfg.AsyncQueueSize = *(flagPtr.(*int))
+		case LOGGER_ARGS_ASYNC_OVERFLOW_POLICY:
+			cfg.AsyncOverflowPolicy = *(flagPtr.(*string))
+		case LOGGER_ARGS_ASYNC_DROP_BELOW_LEVEL:
+			cfg.AsyncDropBelowLevel = *(flagPtr.(*string))
+		case LOGGER_ARGS_ASYNC_FLUSH_INTERVAL:
+			cfg.AsyncFlushInterval = *(flagPtr.(*string))
 		}
 	}
 }
 
 // Apply logger args to config. If onlySet is defined, then apply only those
 // that were set on the command line and not their default value.
-func ApplyLoggerArgs(cfg *LoggerConfig, onlySet bool) *LoggerConfig {
+func (args *LoggerArgs) Apply(cfg *LoggerConfig, onlySet bool) *LoggerConfig {
 	if cfg == nil {
 		cfg = DefaultLoggerConfig()
 	}
 	if onlySet {
-		flag.Visit(func(f *flag.Flag) { applyFlag(f.Name, cfg) })
+		args.fs.visitSet(func(name string) {
+			if strings.HasPrefix(name, args.prefix) {
+				args.applyFlag(name[len(args.prefix):], cfg)
+			}
+		})
 	} else {
-		for name := range loggerFlags {
-			applyFlag(name, cfg)
+		for name := range args.flags {
+			args.applyFlag(name, cfg)
 		}
 	}
 	return cfg
 }
 
+func (args *LoggerArgs) ApplySet(cfg *LoggerConfig) {
+	args.Apply(cfg, true)
+}
+
+func (args *LoggerArgs) LoggerConfig() *LoggerConfig {
+	return args.Apply(nil, false)
+}
+
+// The logger args registered on the global flag.CommandLine:
+var commandLineLoggerArgs *LoggerArgs
+
+func EnableLoggerArgs() {
+	commandLineLoggerArgs = NewLoggerArgs(flag.CommandLine, "")
+}
+
+// Apply logger args to config. If onlySet is defined, then apply only those
+// that were set on the command line and not their default value.
+func ApplyLoggerArgs(cfg *LoggerConfig, onlySet bool) *LoggerConfig {
+	if commandLineLoggerArgs == nil {
+		if cfg == nil {
+			cfg = DefaultLoggerConfig()
+		}
+		return cfg
+	}
+	return commandLineLoggerArgs.Apply(cfg, onlySet)
+}
+
 func ApplySetLoggerArgs(cfg *LoggerConfig) {
 	ApplyLoggerArgs(cfg, true)
 }
@@ -0,0 +1,65 @@
+package logrusx_test
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+
+	"github.com/bgp59/logrusx"
+	"github.com/spf13/pflag"
+)
+
+func TestLoggerArgs(t *testing.T) {
+	baseCfg := &logrusx.LoggerConfig{
+		UseJson:             false,
+		Level:               "warn",
+		LogFile:             "/tmp/base.log",
+		LogFileMaxSizeMB:    3,
+		LogFileMaxBackupNum: 2,
+	}
+	args := []string{"--svc-log-level=debug", "--svc-log-file-max-backup-num=5"}
+
+	for _, tc := range []struct {
+		name    string
+		newArgs func() (*logrusx.LoggerArgs, func([]string) error)
+	}{
+		{
+			name: "flag",
+			newArgs: func() (*logrusx.LoggerArgs, func([]string) error) {
+				fs := flag.NewFlagSet("test", flag.ContinueOnError)
+				return logrusx.NewLoggerArgs(fs, "svc"), fs.Parse
+			},
+		},
+		{
+			name: "pflag",
+			newArgs: func() (*logrusx.LoggerArgs, func([]string) error) {
+				fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+				return logrusx.NewLoggerPFlagArgs(fs, "svc-"), fs.Parse
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			loggerArgs, parse := tc.newArgs()
+			if err := parse(args); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := *baseCfg
+			loggerArgs.ApplySet(&cfg)
+			wantCfg := *baseCfg
+			wantCfg.Level = "debug"
+			wantCfg.LogFileMaxBackupNum = 5
+			if !reflect.DeepEqual(&wantCfg, &cfg) {
+				t.Fatalf("ApplySet:\n want: %#v\n  got: %#v", &wantCfg, &cfg)
+			}
+
+			gotCfg := loggerArgs.LoggerConfig()
+			wantCfg = *logrusx.DefaultLoggerConfig()
+			wantCfg.Level = "debug"
+			wantCfg.LogFileMaxBackupNum = 5
+			if !reflect.DeepEqual(&wantCfg, gotCfg) {
+				t.Fatalf("LoggerConfig:\n want: %#v\n  got: %#v", &wantCfg, gotCfg)
+			}
+		})
+	}
+}
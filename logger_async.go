@@ -0,0 +1,130 @@
+// Asynchronous writing support
+
+package logrusx
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	logrusx_internal "github.com/bgp59/logrusx/internal"
+)
+
+const (
+	LOGGER_ASYNC_OVERFLOW_BLOCK            = logrusx_internal.ASYNC_OVERFLOW_BLOCK
+	LOGGER_ASYNC_OVERFLOW_DROP_NEWEST      = logrusx_internal.ASYNC_OVERFLOW_DROP_NEWEST
+	LOGGER_ASYNC_OVERFLOW_DROP_OLDEST      = logrusx_internal.ASYNC_OVERFLOW_DROP_OLDEST
+	LOGGER_ASYNC_OVERFLOW_DROP_BELOW_LEVEL = logrusx_internal.ASYNC_OVERFLOW_DROP_BELOW_LEVEL
+
+	// The buffer size for stream outputs, i.e. files, stdout and stderr:
+	LOGGER_ASYNC_BUFFER_SIZE = 64 * 1024
+)
+
+var loggerAsyncOverflowPolicies = logrusx_internal.AsyncOverflowPolicies
+
+func isValidAsyncOverflowPolicy(policy string) bool {
+	for _, validPolicy := range loggerAsyncOverflowPolicies {
+		if policy == validPolicy {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAsyncFlushInterval(interval string) (time.Duration, error) {
+	d, err := time.ParseDuration(interval)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid flush interval %q, must be a duration > 0", interval)
+	}
+	return d, nil
+}
+
+// Start an async writer for each output. Should be called after all the
+// outputs were successfully built, since the writers cannot be discarded
+// w/o being closed.
+func (logger *CollectableLogger) newAsyncWriters(cfg *LoggerConfig, outputs []*loggerOutput) error {
+	dropLevel, err := logrus.ParseLevel(cfg.AsyncDropBelowLevel)
+	if err != nil {
+		return err
+	}
+	flushInterval, err := parseAsyncFlushInterval(cfg.AsyncFlushInterval)
+	if err != nil {
+		return err
+	}
+	for _, output := range outputs {
+		bufSize := LOGGER_ASYNC_BUFFER_SIZE
+		if output.cfg != nil && (isSyslogDestination(output.cfg.LogFile) || isJournaldDestination(output.cfg.LogFile)) {
+			// One message per write:
+			bufSize = 0
+		}
+		output.async = logrusx_internal.NewAsyncWriter(
+			output.out,
+			cfg.AsyncQueueSize,
+			cfg.AsyncOverflowPolicy,
+			dropLevel,
+			bufSize,
+			flushInterval,
+		)
+	}
+	return nil
+}
+
+// Drain and close the async writers for the outputs, if any. The dropped
+// counters are preserved.
+func (logger *CollectableLogger) closeAsyncWriters(outputs []*loggerOutput) error {
+	errs := make([]error, 0)
+	for _, output := range outputs {
+		if output.async == nil {
+			continue
+		}
+		if err := output.async.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		logger.asyncDropped.Add(output.async.Dropped())
+	}
+	return errors.Join(errs...)
+}
+
+// Write all the records queued so far and flush the buffers, for async mode,
+// otherwise this is a no-op.
+func (logger *CollectableLogger) Flush() error {
+	errs := make([]error, 0)
+	for _, output := range logger.outputsFormatter.getOutputs() {
+		if output.async != nil {
+			if err := output.async.Flush(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Drain the queues and stop the async writers, to be called at shutdown. The
+// logger remains usable, the subsequent records are written synchronously. An
+// error means that some of the queued records may have been lost.
+func (logger *CollectableLogger) Close() error {
+	logger.cfgMu.Lock()
+	defer logger.cfgMu.Unlock()
+	outputs := logger.outputsFormatter.getOutputs()
+	newOutputs := make([]*loggerOutput, len(outputs))
+	for i, output := range outputs {
+		outputCopy := *output
+		outputCopy.async = nil
+		newOutputs[i] = &outputCopy
+	}
+	logger.outputsFormatter.setOutputs(newOutputs)
+	return logger.closeAsyncWriters(outputs)
+}
+
+// The number of records dropped, in async mode, due to the queues being full:
+func (logger *CollectableLogger) GetAsyncDroppedCount() uint64 {
+	dropped := logger.asyncDropped.Load()
+	for _, output := range logger.outputsFormatter.getOutputs() {
+		if output.async != nil {
+			dropped += output.async.Dropped()
+		}
+	}
+	return dropped
+}
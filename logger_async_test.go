@@ -0,0 +1,95 @@
+package logrusx_test
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/bgp59/logrusx"
+)
+
+func TestLoggerAsync(t *testing.T) {
+	logFile := path.Join(t.TempDir(), "app.log")
+	logger := logrusx.NewCollectableLogger()
+	cfg := logrusx.DefaultLoggerConfig()
+	cfg.LogFile = logFile
+	cfg.Async = true
+	cfg.AsyncFlushInterval = "1h"
+	if err := logger.SetLogger(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer logger.SetLogger(&logrusx.LoggerConfig{LogFile: "stderr"})
+
+	readLines := func() []string {
+		buf, err := os.ReadFile(logFile)
+		if os.IsNotExist(err) {
+			// The file is created at the 1st write:
+			return nil
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(buf) == 0 {
+			return nil
+		}
+		return strings.Split(strings.TrimSpace(string(buf)), "\n")
+	}
+
+	logger.Info("record 1")
+	logger.NewCompLogger("comp").Info("record 2")
+	// Buffered until flushed:
+	if lines := readLines(); len(lines) != 0 {
+		t.Fatalf("%s: want no lines before flush, got %q", logFile, lines)
+	}
+	if err := logger.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if lines := readLines(); len(lines) != 2 {
+		t.Fatalf("%s: want 2 lines after flush, got %q", logFile, lines)
+	}
+
+	logger.Info("record 3")
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if lines := readLines(); len(lines) != 3 {
+		t.Fatalf("%s: want 3 lines after close, got %q", logFile, lines)
+	}
+	// Synchronous after close:
+	logger.Info("record 4")
+	lines := readLines()
+	if len(lines) != 4 {
+		t.Fatalf("%s: want 4 lines after close, got %q", logFile, lines)
+	}
+	for i, line := range lines {
+		if want := "record " + string(rune('1'+i)); !strings.Contains(line, want) {
+			t.Errorf("%s line# %d: want %q, got %q", logFile, i+1, want, line)
+		}
+	}
+	if got := logger.GetAsyncDroppedCount(); got != 0 {
+		t.Errorf("dropped: want 0, got %d", got)
+	}
+}
+
+func TestLoggerAsyncConfigErrors(t *testing.T) {
+	for _, tc := range []struct {
+		yaml        string
+		wantErrSubs []string
+	}{
+		{"async: true\nasync_queue_size: 0\n", []string{"line 2", "async_queue_size"}},
+		{"async: true\nasync_overflow_policy: spill\n", []string{"line 2", "async_overflow_policy", "drop_oldest"}},
+		{"async: true\nasync_drop_below_level: loud\n", []string{"line 2", "async_drop_below_level"}},
+		{"async: true\nasync_flush_interval: 0s\n", []string{"line 2", "async_flush_interval"}},
+	} {
+		_, err := logrusx.LoadLoggerConfigYAML([]byte(tc.yaml))
+		if err == nil {
+			t.Fatalf("%q: want error, got nil", tc.yaml)
+		}
+		for _, sub := range tc.wantErrSubs {
+			if !strings.Contains(err.Error(), sub) {
+				t.Errorf("error %q: missing %q", err, sub)
+			}
+		}
+	}
+}
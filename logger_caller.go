@@ -0,0 +1,132 @@
+// Caller skip for logging helpers, such that the reported source file:line#
+// is the one of the helper's caller, testing.T.Helper style.
+
+package logrusx
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The field holding the extra caller skip for an entry, see WithCallerSkip; it
+// is removed before formatting, but it is visible to the hooks:
+const LOGGER_CALLER_SKIP_FIELD_NAME = "logrusx.caller_skip"
+
+// The max depth of the stack, from the formatter up, searched for the caller
+// reported by logrus:
+const callerSearchMaxDepth = 64
+
+// The functions marked as helpers, shared by the root and the component
+// loggers:
+type callerHelpers struct {
+	funcs sync.Map // function name -> true
+	// The number of helpers, for skipping the stack walk if none:
+	n atomic.Int32
+}
+
+func (h *callerHelpers) add(function string) {
+	if _, loaded := h.funcs.LoadOrStore(function, true); !loaded {
+		h.n.Add(1)
+	}
+}
+
+func (h *callerHelpers) isHelper(function string) bool {
+	_, ok := h.funcs.Load(function)
+	return ok
+}
+
+// Move the caller reported by logrus up the stack, by the skip from the entry,
+// if any, and then past the helpers. It should be invoked from the formatter,
+// i.e. on the logging goroutine, while the stack still contains the caller.
+// The entry is the one private to the logrus.Entry.log invocation, so it is
+// updated in place. The stack is walked only if needed, i.e. for a skip or if
+// the reported caller is a helper.
+func (h *callerHelpers) adjustCaller(entry *logrus.Entry) {
+	skip := 0
+	if val, ok := entry.Data[LOGGER_CALLER_SKIP_FIELD_NAME]; ok {
+		skip, _ = val.(int)
+		delete(entry.Data, LOGGER_CALLER_SKIP_FIELD_NAME)
+	}
+	if !entry.HasCaller() {
+		return
+	}
+	if skip <= 0 && (h.n.Load() == 0 || !h.isHelper(entry.Caller.Function)) {
+		return
+	}
+
+	// Retry w/ a deeper stack if the latter was truncated before the
+	// adjustment was complete:
+	for depth := callerSearchMaxDepth + skip; ; depth *= 2 {
+		pcs := make([]uintptr, depth)
+		n := runtime.Callers(2, pcs)
+		caller, complete := h.findCaller(runtime.CallersFrames(pcs[:n]), entry.Caller, skip)
+		if complete || n < depth {
+			if caller != nil {
+				entry.Caller = caller
+			}
+			return
+		}
+	}
+}
+
+// Locate the reported caller in the frames and move up from it, see
+// adjustCaller. Return the adjusted caller, nil if the reported one is not
+// found, and whether the adjustment is complete, i.e. it did not run out of
+// frames.
+func (h *callerHelpers) findCaller(frames *runtime.Frames, reported *runtime.Frame, skip int) (*runtime.Frame, bool) {
+	for {
+		frame, more := frames.Next()
+		if frame.PC == reported.PC && frame.Function == reported.Function {
+			break
+		}
+		if !more {
+			return nil, false
+		}
+	}
+	caller := *reported
+	for skip > 0 || h.isHelper(caller.Function) {
+		// Past the last frame, Next returns an empty one:
+		frame, _ := frames.Next()
+		if frame.Function == "" {
+			return &caller, false
+		}
+		caller = frame
+		if skip > 0 {
+			skip--
+		}
+	}
+	return &caller, true
+}
+
+// Mark the calling function as a logging helper, such that the records logged
+// from it, via the root or the component loggers, report the file:line# of
+// its caller instead. It applies to all the invocations of the function,
+// regardless of the goroutine.
+func (logger *CollectableLogger) Helper() {
+	pcs := make([]uintptr, 1)
+	if runtime.Callers(2, pcs) == 0 {
+		return
+	}
+	frame, _ := runtime.CallersFrames(pcs).Next()
+	if frame.Function != "" {
+		logger.callerHelpers.add(frame.Function)
+	}
+}
+
+// Return an entry whose records report the caller `skip` frames above the
+// actual one, e.g. 1 for a helper reporting its caller. The skip is applied
+// before the one for the functions marked via Helper.
+func (logger *CollectableLogger) WithCallerSkip(skip int) *logrus.Entry {
+	return logger.WithField(LOGGER_CALLER_SKIP_FIELD_NAME, skip)
+}
+
+// As above, for component loggers, or entries in general:
+func EntryWithCallerSkip(entry *logrus.Entry, skip int) *logrus.Entry {
+	if val, ok := entry.Data[LOGGER_CALLER_SKIP_FIELD_NAME].(int); ok {
+		skip += val
+	}
+	return entry.WithField(LOGGER_CALLER_SKIP_FIELD_NAME, skip)
+}
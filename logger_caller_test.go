@@ -0,0 +1,90 @@
+package logrusx_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/bgp59/logrusx"
+	"github.com/sirupsen/logrus"
+)
+
+// Return the line# of the caller:
+func testCallerLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+func testLogErr(logger *logrusx.CollectableLogger, entry *logrus.Entry, err error) {
+	logger.Helper()
+	entry.Error(err)
+}
+
+func testLogErrNested(logger *logrusx.CollectableLogger, entry *logrus.Entry, err error) {
+	logger.Helper()
+	testLogErr(logger, entry, err)
+}
+
+func testLogErrSkip(entry *logrus.Entry, err error) {
+	logrusx.EntryWithCallerSkip(entry, 1).Error(err)
+}
+
+func testLogErrRootSkip(logger *logrusx.CollectableLogger, err error) {
+	logger.WithCallerSkip(1).Error(err)
+}
+
+// A chain of helpers deeper than the initial stack search depth:
+func testLogErrRecursive(logger *logrusx.CollectableLogger, entry *logrus.Entry, depth int) {
+	logger.Helper()
+	if depth > 0 {
+		testLogErrRecursive(logger, entry, depth-1)
+		return
+	}
+	entry.Error(nil)
+}
+
+func TestLoggerCallerSkip(t *testing.T) {
+	logger := logrusx.NewCollectableLogger()
+	cfg := logrusx.DefaultLoggerConfig()
+	cfg.Format = logrusx.LOGGER_FORMAT_JSON
+	if err := logger.SetLogger(cfg); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	logger.SetOutput(buf)
+	compLogger := logger.NewCompLogger("comp")
+
+	for _, tc := range []struct {
+		name string
+		log  func() int
+	}{
+		{"helper", func() int { testLogErr(logger, logger.WithField("k", "v"), nil); return testCallerLine() }},
+		{"helper_comp", func() int { testLogErr(logger, compLogger, nil); return testCallerLine() }},
+		{"helper_nested", func() int { testLogErrNested(logger, compLogger, nil); return testCallerLine() }},
+		{"skip", func() int { testLogErrSkip(logger.WithField("k", "v"), nil); return testCallerLine() }},
+		{"skip_comp", func() int { testLogErrSkip(compLogger, nil); return testCallerLine() }},
+		{"skip_root", func() int { testLogErrRootSkip(logger, nil); return testCallerLine() }},
+		{"skip_root_0", func() int { logger.WithCallerSkip(0).Info(); return testCallerLine() }},
+		{"helper_recursive", func() int { testLogErrRecursive(logger, compLogger, 100); return testCallerLine() }},
+		// Not a helper, after helpers were marked:
+		{"plain", func() int { logger.Info(); return testCallerLine() }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			buf.Reset()
+			wantFile := fmt.Sprintf("logger_caller_test.go:%d", tc.log())
+			record := make(map[string]any)
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("%q: %v", buf, err)
+			}
+			if gotFile, _ := record["file"].(string); !strings.HasSuffix(gotFile, wantFile) {
+				t.Errorf("want %q, got %q", wantFile, gotFile)
+			}
+			if _, ok := record[logrusx.LOGGER_CALLER_SKIP_FIELD_NAME]; ok {
+				t.Errorf("unexpected %q field", logrusx.LOGGER_CALLER_SKIP_FIELD_NAME)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+// Component sub-loggers and their registry
+
+package logrusx
+
+import (
+	"io"
+	"sort"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+
+	logrusx_internal "github.com/bgp59/logrusx/internal"
+)
+
+// Each component has its own logrus.Logger, such that it can be filtered
+// against its own level, while sharing the output, formatter, hooks, etc. with
+// the root logger. The latter will propagate its changes to all the component
+// loggers.
+type compLogger struct {
+	name   string
+	logger *logrus.Logger
+	// The shared output, used when not muted:
+	out io.Writer
+	// The effective level, when not muted:
+	level logrus.Level
+	// Whether the level was set explicitly for the component, otherwise it
+	// follows the root level:
+	hasLevel bool
+	// Whether all the records should be discarded:
+	muted bool
+	// Record counts, indexed by level:
+	recordCounts [logrus.TraceLevel + 1]atomic.Uint64
+}
+
+// Wrap the shared formatter to count the records for the component:
+type compFormatter struct {
+	comp      *compLogger
+	formatter logrus.Formatter
+}
+
+func (f *compFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if entry.Level <= logrus.TraceLevel {
+		f.comp.recordCounts[entry.Level].Add(1)
+	}
+	return f.formatter.Format(entry)
+}
+
+func (comp *compLogger) setLevel(level logrus.Level) {
+	comp.level = level
+	if !comp.muted {
+		comp.logger.SetLevel(level)
+	}
+}
+
+func (comp *compLogger) setFormatter(formatter logrus.Formatter) {
+	comp.logger.SetFormatter(&compFormatter{comp, formatter})
+}
+
+func (comp *compLogger) setMuted(muted bool) {
+	comp.muted = muted
+	if muted {
+		// Panic level records cannot be disabled, they will be discarded:
+		comp.logger.SetLevel(logrus.PanicLevel)
+		comp.logger.SetOutput(io.Discard)
+	} else {
+		comp.logger.SetOutput(comp.out)
+		comp.logger.SetLevel(comp.level)
+	}
+}
+
+// Return the component logger for compName, creating it as needed. The logger
+// is an entry with the "comp" field set to compName and its level is the one
+// set for the component in LoggerConfig.CompLevels, if any, or the root level
+// otherwise.
+func (logger *CollectableLogger) NewCompLogger(compName string) *logrus.Entry {
+	logger.compMu.Lock()
+	defer logger.compMu.Unlock()
+
+	comp := logger.comps[compName]
+	if comp == nil {
+		comp = &compLogger{
+			name: compName,
+			logger: &logrus.Logger{
+				Out:          logger.out,
+				Hooks:        logger.Hooks,
+				ReportCaller: logger.ReportCaller,
+				ExitFunc:     logger.ExitFunc,
+			},
+			out: logger.out,
+		}
+		comp.setFormatter(logger.Formatter)
+		if level, ok := logger.compLevels[compName]; ok {
+			comp.setLevel(level)
+			comp.hasLevel = true
+		} else {
+			comp.setLevel(logger.Logger.GetLevel())
+		}
+		logger.comps[compName] = comp
+	}
+	return comp.logger.WithField(logrusx_internal.LOGGER_COMPONENT_FIELD_NAME, compName)
+}
+
+// Replace the explicit component levels; the components not in the map will
+// revert to the root level.
+func (logger *CollectableLogger) setCompLevels(compLevels map[string]logrus.Level) {
+	logger.compMu.Lock()
+	defer logger.compMu.Unlock()
+
+	logger.compLevels = compLevels
+	rootLevel := logger.Logger.GetLevel()
+	for compName, comp := range logger.comps {
+		if level, ok := compLevels[compName]; ok {
+			comp.setLevel(level)
+			comp.hasLevel = true
+		} else {
+			comp.setLevel(rootLevel)
+			comp.hasLevel = false
+		}
+	}
+}
+
+// Get the effective level for a component, i.e. its explicit level, if set, or
+// the root level otherwise. The level is reported regardless of the muted
+// state.
+func (logger *CollectableLogger) GetCompLevel(compName string) logrus.Level {
+	logger.compMu.Lock()
+	defer logger.compMu.Unlock()
+	if comp := logger.comps[compName]; comp != nil {
+		return comp.level
+	}
+	if level, ok := logger.compLevels[compName]; ok {
+		return level
+	}
+	return logger.Logger.GetLevel()
+}
+
+// Set an explicit level for a component, at runtime. The level applies to the
+// component logger, whether already created or created later on.
+func (logger *CollectableLogger) SetCompLevel(compName string, level logrus.Level) {
+	logger.compMu.Lock()
+	defer logger.compMu.Unlock()
+	logger.compLevels[compName] = level
+	if comp := logger.comps[compName]; comp != nil {
+		comp.setLevel(level)
+		comp.hasLevel = true
+	}
+}
+
+// Remove the explicit level of a component, such that it follows the root
+// level.
+func (logger *CollectableLogger) ResetCompLevel(compName string) {
+	logger.compMu.Lock()
+	defer logger.compMu.Unlock()
+	delete(logger.compLevels, compName)
+	if comp := logger.comps[compName]; comp != nil {
+		comp.setLevel(logger.Logger.GetLevel())
+		comp.hasLevel = false
+	}
+}
+
+// Mute/unmute a component, i.e. discard all its records regardless of level.
+// Return false if there is no such component.
+func (logger *CollectableLogger) SetCompMuted(compName string, muted bool) bool {
+	logger.compMu.Lock()
+	defer logger.compMu.Unlock()
+	comp := logger.comps[compName]
+	if comp == nil {
+		return false
+	}
+	if comp.muted != muted {
+		comp.setMuted(muted)
+	}
+	return true
+}
+
+// Get the list of component names, sorted:
+func (logger *CollectableLogger) GetCompNames() []string {
+	logger.compMu.Lock()
+	defer logger.compMu.Unlock()
+	compNames := make([]string, 0, len(logger.comps))
+	for compName := range logger.comps {
+		compNames = append(compNames, compName)
+	}
+	sort.Strings(compNames)
+	return compNames
+}
+
+// Component info, as returned by the registry:
+type CompInfo struct {
+	Name string `json:"name"`
+	// Effective level name:
+	Level string `json:"level"`
+	// Whether the level was set explicitly or it follows the root:
+	HasLevel bool `json:"has_level"`
+	Muted    bool `json:"muted"`
+	// Record counts by level name, only levels w/ non-zero counts are
+	// included:
+	RecordCounts map[string]uint64 `json:"record_counts"`
+}
+
+// Get the info for a component, nil if there is no such component:
+func (logger *CollectableLogger) GetCompInfo(compName string) *CompInfo {
+	logger.compMu.Lock()
+	defer logger.compMu.Unlock()
+	comp := logger.comps[compName]
+	if comp == nil {
+		return nil
+	}
+	return comp.info()
+}
+
+// Get the info for all components, sorted by name:
+func (logger *CollectableLogger) GetCompInfoList() []*CompInfo {
+	logger.compMu.Lock()
+	defer logger.compMu.Unlock()
+	compInfoList := make([]*CompInfo, 0, len(logger.comps))
+	for _, comp := range logger.comps {
+		compInfoList = append(compInfoList, comp.info())
+	}
+	sort.Slice(compInfoList, func(i, j int) bool { return compInfoList[i].Name < compInfoList[j].Name })
+	return compInfoList
+}
+
+func (comp *compLogger) info() *CompInfo {
+	compInfo := &CompInfo{
+		Name:         comp.name,
+		Level:        comp.level.String(),
+		HasLevel:     comp.hasLevel,
+		Muted:        comp.muted,
+		RecordCounts: make(map[string]uint64),
+	}
+	for level := range comp.recordCounts {
+		if count := comp.recordCounts[level].Load(); count > 0 {
+			compInfo.RecordCounts[logrus.Level(level).String()] = count
+		}
+	}
+	return compInfo
+}
+
+// The equivalent of CollectableLogger.IsEnabledForDebug for component loggers
+// (or entries derived from them); the check is based on the level of the logger
+// behind the entry and it is cheap enough to be used before expensive actions.
+func IsEntryEnabledForDebug(entry *logrus.Entry) bool {
+	return entry.Logger.IsLevelEnabled(logrus.DebugLevel)
+}
@@ -0,0 +1,149 @@
+package logrusx_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bgp59/logrusx"
+	"github.com/sirupsen/logrus"
+)
+
+func TestCompLevels(t *testing.T) {
+	logger := logrusx.NewCollectableLogger()
+	// Create a component logger before the config is applied:
+	dbLogger := logger.NewCompLogger("db")
+
+	err := logger.SetLogger(&logrusx.LoggerConfig{
+		Level:      "info",
+		CompLevels: map[string]string{"db": "debug", "http": "warn"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	logger.SetOutput(buf)
+
+	httpLogger := logger.NewCompLogger("http")
+	otherLogger := logger.NewCompLogger("other")
+
+	for _, tc := range []struct {
+		entry     *logrus.Entry
+		wantLevel logrus.Level
+	}{
+		{dbLogger, logrus.DebugLevel},
+		{httpLogger, logrus.WarnLevel},
+		{otherLogger, logrus.InfoLevel},
+	} {
+		compName := tc.entry.Data["comp"].(string)
+		if got := logger.GetCompLevel(compName); got != tc.wantLevel {
+			t.Errorf("GetCompLevel(%q): want %v, got %v", compName, tc.wantLevel, got)
+		}
+		wantDebug := tc.wantLevel >= logrus.DebugLevel
+		if got := logrusx.IsEntryEnabledForDebug(tc.entry); got != wantDebug {
+			t.Errorf("IsEntryEnabledForDebug(%q): want %v, got %v", compName, wantDebug, got)
+		}
+	}
+
+	dbLogger.Debug("db debug")
+	httpLogger.Info("http info")
+	httpLogger.Warn("http warn")
+	otherLogger.Debug("other debug")
+	otherLogger.Info("other info")
+	logger.Debug("root debug")
+
+	out := buf.String()
+	for _, want := range []string{"db debug", "http warn", "other info"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	for _, notWant := range []string{"http info", "other debug", "root debug"} {
+		if strings.Contains(out, notWant) {
+			t.Errorf("unexpected %q in:\n%s", notWant, out)
+		}
+	}
+
+	// Root level changes should propagate only to components w/o an explicit
+	// level:
+	logger.SetLevel(logrus.ErrorLevel)
+	if got := logger.GetCompLevel("other"); got != logrus.ErrorLevel {
+		t.Errorf("GetCompLevel(%q): want %v, got %v", "other", logrus.ErrorLevel, got)
+	}
+	if got := logger.GetCompLevel("db"); got != logrus.DebugLevel {
+		t.Errorf("GetCompLevel(%q): want %v, got %v", "db", logrus.DebugLevel, got)
+	}
+
+	// Dropping the explicit levels should revert the components to the root
+	// level:
+	err = logger.SetLogger(&logrusx.LoggerConfig{Level: "trace"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := logger.GetCompLevel("http"); got != logrus.TraceLevel {
+		t.Errorf("GetCompLevel(%q): want %v, got %v", "http", logrus.TraceLevel, got)
+	}
+}
+
+func TestCompRegistry(t *testing.T) {
+	logger := logrusx.NewCollectableLogger()
+	buf := &bytes.Buffer{}
+	logger.SetOutput(buf)
+
+	aLogger := logger.NewCompLogger("a")
+	bLogger := logger.NewCompLogger("b")
+	// Same name, same component:
+	logger.NewCompLogger("a")
+
+	wantNames := []string{"a", "b"}
+	gotNames := logger.GetCompNames()
+	if strings.Join(wantNames, ",") != strings.Join(gotNames, ",") {
+		t.Fatalf("GetCompNames: want %q, got %q", wantNames, gotNames)
+	}
+
+	logger.SetCompLevel("a", logrus.DebugLevel)
+	aLogger.Debug("a debug")
+	bLogger.Debug("b debug")
+	bLogger.Info("b info")
+
+	if !logger.SetCompMuted("b", true) {
+		t.Fatal("SetCompMuted(b): want true, got false")
+	}
+	bLogger.Error("b muted error")
+	logger.SetCompMuted("b", false)
+	bLogger.Warn("b warn")
+
+	if logger.SetCompMuted("c", true) {
+		t.Fatal("SetCompMuted(c): want false, got true")
+	}
+
+	out := buf.String()
+	for _, want := range []string{"a debug", "b info", "b warn"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	for _, notWant := range []string{"b debug", "b muted error"} {
+		if strings.Contains(out, notWant) {
+			t.Errorf("unexpected %q in:\n%s", notWant, out)
+		}
+	}
+
+	compInfoList := logger.GetCompInfoList()
+	if len(compInfoList) != 2 {
+		t.Fatalf("len(GetCompInfoList()): want 2, got %d", len(compInfoList))
+	}
+	aInfo, bInfo := compInfoList[0], compInfoList[1]
+	if aInfo.Name != "a" || aInfo.Level != "debug" || !aInfo.HasLevel || aInfo.RecordCounts["debug"] != 1 {
+		t.Errorf("unexpected %#v", aInfo)
+	}
+	if bInfo.Name != "b" || bInfo.Level != "info" || bInfo.HasLevel || bInfo.Muted ||
+		bInfo.RecordCounts["info"] != 1 || bInfo.RecordCounts["warning"] != 1 {
+		t.Errorf("unexpected %#v", bInfo)
+	}
+
+	logger.ResetCompLevel("a")
+	if got := logger.GetCompLevel("a"); got != logrus.InfoLevel {
+		t.Errorf("GetCompLevel(%q): want %v, got %v", "a", logrus.InfoLevel, got)
+	}
+}
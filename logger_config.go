@@ -0,0 +1,227 @@
+// YAML loadable logger config
+
+package logrusx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Load the logger config from a YAML file. See LoadLoggerConfigYAML for
+// details.
+func LoadLoggerConfigFile(cfgFile string) (*LoggerConfig, error) {
+	buf, err := os.ReadFile(cfgFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := LoadLoggerConfigYAML(buf)
+	if err != nil {
+		return nil, fmt.Errorf("file: %q: %w", cfgFile, err)
+	}
+	return cfg, nil
+}
+
+// Load the logger config from YAML content. The config starts from the
+// defaults and it is updated with the keys present in the content. Unknown keys
+// are rejected and the result is validated; the errors will report the
+// offending key and its line#.
+func LoadLoggerConfigYAML(buf []byte) (*LoggerConfig, error) {
+	cfg := DefaultLoggerConfig()
+
+	// Parse into a node first, it will be used for locating the keys for
+	// validation errors:
+	root := &yaml.Node{}
+	err := yaml.Unmarshal(buf, root)
+	if err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		// Empty content, use the defaults:
+		return cfg, nil
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(buf))
+	decoder.KnownFields(true)
+	err = decoder.Decode(cfg)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	err = cfg.validate(func(key string) int { return findYAMLKeyLine(root.Content[0], key) })
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate the config, return the first error encountered, if any.
+func (cfg *LoggerConfig) Validate() error {
+	return cfg.validate(nil)
+}
+
+// Validate the config, using the optional keyLine function to locate the
+// offending key. The key is a `.` separated path, based on the yaml tags.
+func (cfg *LoggerConfig) validate(keyLine func(key string) int) error {
+	errorf := func(key string, format string, args ...any) error {
+		msg := fmt.Sprintf(format, args...)
+		if keyLine != nil {
+			if line := keyLine(key); line > 0 {
+				return fmt.Errorf("line %d: %s: %s", line, key, msg)
+			}
+		}
+		return fmt.Errorf("%s: %s", key, msg)
+	}
+
+	if cfg.Level != "" && !isValidLevelName(cfg.Level) {
+		return errorf("level", "invalid level %q, must be one of %v", cfg.Level, GetLogLevelNames())
+	}
+	if cfg.FuncName != "" && !isValidFuncNameMode(cfg.FuncName) {
+		return errorf("func_name", "invalid mode %q, must be one of %v", cfg.FuncName, loggerFuncNameModes)
+	}
+	if cfg.CallerCacheMaxSize < 0 {
+		return errorf("caller_cache_max_size", "invalid value %d, must be >= 0", cfg.CallerCacheMaxSize)
+	}
+	compNames := make([]string, 0, len(cfg.CompLevels))
+	for compName := range cfg.CompLevels {
+		compNames = append(compNames, compName)
+	}
+	sort.Strings(compNames)
+	for _, compName := range compNames {
+		if levelName := cfg.CompLevels[compName]; !isValidLevelName(levelName) {
+			return errorf(
+				"comp_levels."+compName,
+				"invalid level %q, must be one of %v", levelName, GetLogLevelNames(),
+			)
+		}
+	}
+	if err := cfg.primaryOutputConfig().validate("", errorf); err != nil {
+		return err
+	}
+	for i := range cfg.Outputs {
+		if err := cfg.Outputs[i].validate(fmt.Sprintf("outputs.%d.", i), errorf); err != nil {
+			return err
+		}
+	}
+	// The async settings are relevant only when enabled:
+	if cfg.Async {
+		if cfg.AsyncQueueSize <= 0 {
+			return errorf("async_queue_size", "invalid value %d, must be > 0", cfg.AsyncQueueSize)
+		}
+		if !isValidAsyncOverflowPolicy(cfg.AsyncOverflowPolicy) {
+			return errorf(
+				"async_overflow_policy",
+				"invalid policy %q, must be one of %v", cfg.AsyncOverflowPolicy, loggerAsyncOverflowPolicies,
+			)
+		}
+		if !isValidLevelName(cfg.AsyncDropBelowLevel) {
+			return errorf(
+				"async_drop_below_level",
+				"invalid level %q, must be one of %v", cfg.AsyncDropBelowLevel, GetLogLevelNames(),
+			)
+		}
+		if _, err := parseAsyncFlushInterval(cfg.AsyncFlushInterval); err != nil {
+			return errorf("async_flush_interval", "%v", err)
+		}
+	}
+	return nil
+}
+
+// Validate an output config, the keys are prefixed w/ keyPrefix:
+func (cfg *LoggerOutputConfig) validate(
+	keyPrefix string,
+	errorf func(key string, format string, args ...any) error,
+) error {
+	if cfg.Level != "" && !isValidLevelName(cfg.Level) {
+		return errorf(keyPrefix+"level", "invalid level %q, must be one of %v", cfg.Level, GetLogLevelNames())
+	}
+	if cfg.Format != "" && !isValidFormat(cfg.Format) {
+		return errorf(keyPrefix+"format", "invalid format %q, must be one of %v", cfg.Format, GetLoggerFormatNames())
+	}
+	if err := validateTimestampFormat(cfg.TimestampFormat); err != nil {
+		return errorf(keyPrefix+"timestamp_format", "%v", err)
+	}
+	if err := validateJsonFields(cfg.JsonFieldPreset, nil); err != nil {
+		return errorf(keyPrefix+"json_field_preset", "%v", err)
+	}
+	jsonFieldNames := make([]string, 0, len(cfg.JsonFieldMap))
+	for name := range cfg.JsonFieldMap {
+		jsonFieldNames = append(jsonFieldNames, name)
+	}
+	sort.Strings(jsonFieldNames)
+	for _, name := range jsonFieldNames {
+		if err := validateJsonFields("", map[string]string{name: cfg.JsonFieldMap[name]}); err != nil {
+			return errorf(keyPrefix+"json_field_map."+name, "%v", err)
+		}
+	}
+	if cfg.LogFileMaxSizeMB < 0 {
+		return errorf(keyPrefix+"log_file_max_size_mb", "invalid value %d, must be >= 0", cfg.LogFileMaxSizeMB)
+	}
+	if cfg.LogFileMaxBackupNum < 0 {
+		return errorf(keyPrefix+"log_file_max_backup_num", "invalid value %d, must be >= 0", cfg.LogFileMaxBackupNum)
+	}
+	if cfg.LogFileMaxAgeDays < 0 {
+		return errorf(keyPrefix+"log_file_max_age_days", "invalid value %d, must be >= 0", cfg.LogFileMaxAgeDays)
+	}
+	if _, err := ParseRotateInterval(cfg.LogFileRotateInterval); err != nil {
+		return errorf(keyPrefix+"log_file_rotate_interval", "%v", err)
+	}
+	if isSyslogDestination(cfg.LogFile) {
+		if _, err := parseSyslogDestination(cfg.LogFile); err != nil {
+			return errorf(keyPrefix+"log_file", "%v", err)
+		}
+	}
+	if isJournaldDestination(cfg.LogFile) {
+		if _, err := parseJournaldDestination(cfg.LogFile); err != nil {
+			return errorf(keyPrefix+"log_file", "%v", err)
+		}
+	}
+	return nil
+}
+
+func isValidLevelName(levelName string) bool {
+	// Defer to logrus, it accepts some aliases besides the names, e.g. "warn":
+	_, err := logrus.ParseLevel(levelName)
+	return err == nil
+}
+
+// Locate the line# of a `.` separated key path in a YAML mapping node; the path
+// may contain sequence indexes, e.g. "outputs.1.level". Return 0 if not found.
+func findYAMLKeyLine(node *yaml.Node, key string) int {
+	line := 0
+	for _, name := range strings.Split(key, ".") {
+		if node != nil && node.Kind == yaml.SequenceNode {
+			i, err := strconv.Atoi(name)
+			if err != nil || i < 0 || i >= len(node.Content) {
+				return 0
+			}
+			node = node.Content[i]
+			line = node.Line
+			continue
+		}
+		if node == nil || node.Kind != yaml.MappingNode {
+			return 0
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == name {
+				line = node.Content[i].Line
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return 0
+		}
+		node = next
+	}
+	return line
+}
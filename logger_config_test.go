@@ -0,0 +1,113 @@
+package logrusx_test
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bgp59/logrusx"
+)
+
+func TestLoadLoggerConfigYAML(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		yaml        string
+		wantCfg     *logrusx.LoggerConfig
+		wantErrSubs []string
+	}{
+		{
+			name:    "empty",
+			yaml:    "",
+			wantCfg: logrusx.DefaultLoggerConfig(),
+		},
+		{
+			name: "partial",
+			yaml: "level: debug\nlog_file: /tmp/test.log\n",
+			wantCfg: func() *logrusx.LoggerConfig {
+				cfg := logrusx.DefaultLoggerConfig()
+				cfg.Level = "debug"
+				cfg.LogFile = "/tmp/test.log"
+				return cfg
+			}(),
+		},
+		{
+			name:        "unknown_key",
+			yaml:        "level: debug\nno_such_key: 1\n",
+			wantErrSubs: []string{"line 2", "no_such_key"},
+		},
+		{
+			name:        "invalid_level",
+			yaml:        "use_json: false\nlevel: verbose\n",
+			wantErrSubs: []string{"line 2", "level", "verbose"},
+		},
+		{
+			name: "comp_levels",
+			yaml: "comp_levels:\n  db: debug\n  http: warn\n",
+			wantCfg: func() *logrusx.LoggerConfig {
+				cfg := logrusx.DefaultLoggerConfig()
+				cfg.CompLevels = map[string]string{"db": "debug", "http": "warn"}
+				return cfg
+			}(),
+		},
+		{
+			name:        "invalid_comp_level",
+			yaml:        "comp_levels:\n  db: debug\n  http: loud\n",
+			wantErrSubs: []string{"line 3", "comp_levels.http", "loud"},
+		},
+		{
+			name:        "invalid_rotate_interval",
+			yaml:        "log_file_rotate_interval: weekly\n",
+			wantErrSubs: []string{"line 1", "log_file_rotate_interval", "weekly"},
+		},
+		{
+			name:        "negative_max_age_days",
+			yaml:        "log_file_compress: true\nlog_file_max_age_days: -1\n",
+			wantErrSubs: []string{"line 2", "log_file_max_age_days"},
+		},
+		{
+			name:        "negative_max_size",
+			yaml:        "log_file_max_size_mb: -1\n",
+			wantErrSubs: []string{"line 1", "log_file_max_size_mb"},
+		},
+		{
+			name:        "negative_max_backup_num",
+			yaml:        "level: info\n\nlog_file_max_backup_num: -2\n",
+			wantErrSubs: []string{"line 3", "log_file_max_backup_num"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := logrusx.LoadLoggerConfigYAML([]byte(tc.yaml))
+			if tc.wantErrSubs != nil {
+				if err == nil {
+					t.Fatalf("want error, got nil")
+				}
+				for _, sub := range tc.wantErrSubs {
+					if !strings.Contains(err.Error(), sub) {
+						t.Errorf("error %q: missing %q", err, sub)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.wantCfg, cfg) {
+				t.Fatalf("cfg:\n want: %#v\n  got: %#v", tc.wantCfg, cfg)
+			}
+		})
+	}
+}
+
+func TestLoadLoggerConfigFile(t *testing.T) {
+	cfgFile := path.Join(t.TempDir(), "logger.yaml")
+	err := os.WriteFile(cfgFile, []byte("level: bogus\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = logrusx.LoadLoggerConfigFile(cfgFile)
+	if err == nil || !strings.Contains(err.Error(), cfgFile) {
+		t.Fatalf("want error mentioning %q, got %v", cfgFile, err)
+	}
+}
@@ -0,0 +1,179 @@
+// Environment variables for logger
+
+// The environment variable names are derived from the command line args names,
+// upper cased, with `-` replaced by `_` and with an optional prefix, e.g. for
+// "MYAPP": MYAPP_LOG_LEVEL, MYAPP_LOG_FILE, MYAPP_LOG_USE_JSON, etc.
+//
+// The intended precedence order, lowest to highest, is:
+//   defaults < config file < environment < command line args
+// i.e.:
+//   cfg, err := LoadLoggerConfigFile(cfgFile)
+//   ...
+//   cfg, err = ApplyLoggerEnv(cfg, "MYAPP")
+//   ...
+//   ApplySetLoggerArgs(cfg)
+
+package logrusx
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+var loggerEnvArgNames = []string{
+	LOGGER_ARGS_USE_JSON,
+	LOGGER_ARGS_FORMAT,
+	LOGGER_ARGS_TIMESTAMP_FORMAT,
+	LOGGER_ARGS_TIMESTAMP_UTC,
+	LOGGER_ARGS_DISABLE_TIMESTAMP,
+	LOGGER_ARGS_JSON_FIELD_PRESET,
+	LOGGER_ARGS_FIELD_ORDER_LEADING,
+	LOGGER_ARGS_FIELD_ORDER_TRAILING,
+	LOGGER_ARGS_FIELD_ORDER_UNSORTED,
+	LOGGER_ARGS_LEVEL,
+	LOGGER_ARGS_DISABALE_SRC_FILE,
+	LOGGER_ARGS_SRC_FILE_MODULE_PATH,
+	LOGGER_ARGS_SRC_FILE_MODULE_VERSION,
+	LOGGER_ARGS_FUNC_NAME,
+	LOGGER_ARGS_CALLER_CACHE_MAX_SIZE,
+	LOGGER_ARGS_LOG_FILE,
+	LOGGER_ARGS_LOG_FILE_MAX_SIZE_MB,
+	LOGGER_ARGS_LOG_FILE_MAX_BACKUP_NUM,
+	LOGGER_ARGS_LOG_FILE_ROTATE_INTERVAL,
+	LOGGER_ARGS_LOG_FILE_ROTATE_UTC,
+	LOGGER_ARGS_LOG_FILE_MAX_AGE_DAYS,
+	LOGGER_ARGS_LOG_FILE_COMPRESS,
+	LOGGER_ARGS_LOG_FILE_LOCAL_TIME,
+	LOGGER_ARGS_LOG_FILE_EXTERNAL_ROTATE,
+	LOGGER_ARGS_ASYNC,
+	LOGGER_ARGS_ASYNC_QUEUE_SIZE,
+	LOGGER_ARGS_ASYNC_OVERFLOW_POLICY,
+	LOGGER_ARGS_ASYNC_DROP_BELOW_LEVEL,
+	LOGGER_ARGS_ASYNC_FLUSH_INTERVAL,
+}
+
+// Get the environment variable name for a command line arg name:
+func GetLoggerEnvName(prefix string, argName string) string {
+	name := strings.ToUpper(strings.ReplaceAll(argName, "-", "_"))
+	if prefix != "" && !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+	return prefix + name
+}
+
+func applyEnv(name string, value string, cfg *LoggerConfig) error {
+	var err error
+	switch name {
+	case LOGGER_ARGS_USE_JSON:
+		cfg.UseJson, err = strconv.ParseBool(value)
+	case LOGGER_ARGS_FORMAT:
+		if value == "" || isValidFormat(value) {
+			cfg.Format = value
+		} else {
+			err = fmt.Errorf("invalid format, must be one of %v", GetLoggerFormatNames())
+		}
+	case LOGGER_ARGS_TIMESTAMP_FORMAT:
+		if err = validateTimestampFormat(value); err == nil {
+			cfg.TimestampFormat = value
+		}
+	case LOGGER_ARGS_TIMESTAMP_UTC:
+		cfg.TimestampUTC, err = strconv.ParseBool(value)
+	case LOGGER_ARGS_DISABLE_TIMESTAMP:
+		cfg.DisableTimestamp, err = strconv.ParseBool(value)
+	case LOGGER_ARGS_JSON_FIELD_PRESET:
+		if err = validateJsonFields(value, nil); err == nil {
+			cfg.JsonFieldPreset = value
+		}
+	case LOGGER_ARGS_FIELD_ORDER_LEADING:
+		cfg.FieldOrderLeading = parseFieldKeyList(value)
+	case LOGGER_ARGS_FIELD_ORDER_TRAILING:
+		cfg.FieldOrderTrailing = parseFieldKeyList(value)
+	case LOGGER_ARGS_FIELD_ORDER_UNSORTED:
+		cfg.FieldOrderUnsorted, err = strconv.ParseBool(value)
+	case LOGGER_ARGS_LEVEL:
+		if _, err = logrus.ParseLevel(value); err == nil {
+			cfg.Level = value
+		}
+	case LOGGER_ARGS_DISABALE_SRC_FILE:
+		cfg.DisableSrcFile, err = strconv.ParseBool(value)
+	case LOGGER_ARGS_SRC_FILE_MODULE_PATH:
+		cfg.SrcFileModulePath, err = strconv.ParseBool(value)
+	case LOGGER_ARGS_SRC_FILE_MODULE_VERSION:
+		cfg.SrcFileModuleVersion, err = strconv.ParseBool(value)
+	case LOGGER_ARGS_FUNC_NAME:
+		if value == "" || isValidFuncNameMode(value) {
+			cfg.FuncName = value
+		} else {
+			err = fmt.Errorf("invalid mode, must be one of %v", loggerFuncNameModes)
+		}
+	case LOGGER_ARGS_CALLER_CACHE_MAX_SIZE:
+		cfg.CallerCacheMaxSize, err = strconv.Atoi(value)
+	case LOGGER_ARGS_LOG_FILE:
+		cfg.LogFile = value
+	case LOGGER_ARGS_LOG_FILE_MAX_SIZE_MB:
+		cfg.LogFileMaxSizeMB, err = strconv.Atoi(value)
+	case LOGGER_ARGS_LOG_FILE_MAX_BACKUP_NUM:
+		cfg.LogFileMaxBackupNum, err = strconv.Atoi(value)
+	case LOGGER_ARGS_LOG_FILE_ROTATE_INTERVAL:
+		if _, err = ParseRotateInterval(value); err == nil {
+			cfg.LogFileRotateInterval = value
+		}
+	case LOGGER_ARGS_LOG_FILE_ROTATE_UTC:
+		cfg.LogFileRotateUTC, err = strconv.ParseBool(value)
+	case LOGGER_ARGS_LOG_FILE_MAX_AGE_DAYS:
+		cfg.LogFileMaxAgeDays, err = strconv.Atoi(value)
+	case LOGGER_ARGS_LOG_FILE_COMPRESS:
+		cfg.LogFileCompress, err = strconv.ParseBool(value)
+	case LOGGER_ARGS_LOG_FILE_LOCAL_TIME:
+		cfg.LogFileLocalTime, err = strconv.ParseBool(value)
+	case LOGGER_ARGS_LOG_FILE_EXTERNAL_ROTATE:
+		cfg.LogFileExternalRotate, err = strconv.ParseBool(value)
+	case LOGGER_ARGS_ASYNC:
+		cfg.Async, err = strconv.ParseBool(value)
+	case LOGGER_ARGS_ASYNC_QUEUE_SIZE:
+		cfg.AsyncQueueSize, err = strconv.Atoi(value)
+	case LOGGER_ARGS_ASYNC_OVERFLOW_POLICY:
+		if isValidAsyncOverflowPolicy(value) {
+			cfg.AsyncOverflowPolicy = value
+		} else {
+			err = fmt.Errorf("invalid policy, must be one of %v", loggerAsyncOverflowPolicies)
+		}
+	case LOGGER_ARGS_ASYNC_DROP_BELOW_LEVEL:
+		if _, err = logrus.ParseLevel(value); err == nil {
+			cfg.AsyncDropBelowLevel = value
+		}
+	case LOGGER_ARGS_ASYNC_FLUSH_INTERVAL:
+		if _, err = parseAsyncFlushInterval(value); err == nil {
+			cfg.AsyncFlushInterval = value
+		}
+	}
+	return err
+}
+
+// Apply the logger environment variables to config; only the variables that
+// are set are applied. If cfg is nil then the default config is used as a
+// starting point. Parse errors are reported for all the offending variables.
+func ApplyLoggerEnv(cfg *LoggerConfig, prefix string) (*LoggerConfig, error) {
+	if cfg == nil {
+		cfg = DefaultLoggerConfig()
+	}
+	errs := make([]string, 0)
+	for _, argName := range loggerEnvArgNames {
+		envName := GetLoggerEnvName(prefix, argName)
+		value, ok := os.LookupEnv(envName)
+		if !ok {
+			continue
+		}
+		if err := applyEnv(argName, value, cfg); err != nil {
+			errs = append(errs, fmt.Sprintf("%s=%q: %v", envName, value, err))
+		}
+	}
+	if len(errs) > 0 {
+		return cfg, fmt.Errorf("invalid logger env: %s", strings.Join(errs, ", "))
+	}
+	return cfg, nil
+}
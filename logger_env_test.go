@@ -0,0 +1,107 @@
+package logrusx_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bgp59/logrusx"
+)
+
+func TestApplyLoggerEnv(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		env         map[string]string
+		wantCfg     *logrusx.LoggerConfig
+		wantErrSubs []string
+	}{
+		{
+			name:    "none",
+			wantCfg: logrusx.DefaultLoggerConfig(),
+		},
+		{
+			name: "all",
+			env: map[string]string{
+				"MYAPP_LOG_USE_JSON":                "false",
+				"MYAPP_LOG_LEVEL":                   "debug",
+				"MYAPP_LOG_DISABLE_SRC_FILE":        "true",
+				"MYAPP_LOG_FILE":                    "/tmp/test.log",
+				"MYAPP_LOG_FILE_MAX_SIZE_MB":        "5",
+				"MYAPP_LOG_FILE_MAX_BACKUP_NUM":     "7",
+				"MYAPP_LOG_ASYNC":                   "true",
+				"MYAPP_LOG_ASYNC_OVERFLOW_POLICY":   "drop_oldest",
+				"MYAPP_LOG_TIMESTAMP_FORMAT":        "epoch_milli",
+				"MYAPP_LOG_TIMESTAMP_UTC":           "true",
+				"MYAPP_LOG_JSON_FIELD_PRESET":       "gcp",
+				"MYAPP_LOG_FIELD_ORDER_LEADING":     "request_id, time",
+				"MYAPP_LOG_FIELD_ORDER_TRAILING":    "",
+				"MYAPP_LOG_FUNC_NAME":               "method",
+				"MYAPP_LOG_CALLER_CACHE_MAX_SIZE":   "1024",
+				"OTHERAPP_LOG_LEVEL":                "trace",
+				"MYAPP_LOG_FILE_MAX_BACKUP_NUM_OLD": "x",
+			},
+			wantCfg: &logrusx.LoggerConfig{
+				UseJson:             false,
+				TimestampFormat:     logrusx.LOGGER_TIMESTAMP_EPOCH_MILLI,
+				TimestampUTC:        true,
+				JsonFieldPreset:     logrusx.LOGGER_JSON_FIELD_PRESET_GCP,
+				FieldOrderLeading:   []string{"request_id", "time"},
+				FieldOrderTrailing:  []string{},
+				FuncName:            logrusx.LOGGER_FUNC_NAME_METHOD,
+				CallerCacheMaxSize:  1024,
+				Level:               "debug",
+				DisableSrcFile:      true,
+				LogFile:             "/tmp/test.log",
+				LogFileMaxSizeMB:    5,
+				LogFileMaxBackupNum: 7,
+				Async:               true,
+				AsyncQueueSize:      logrusx.LOGGER_CONFIG_ASYNC_QUEUE_SIZE_DEFAULT,
+				AsyncOverflowPolicy: logrusx.LOGGER_ASYNC_OVERFLOW_DROP_OLDEST,
+				AsyncDropBelowLevel: logrusx.LOGGER_CONFIG_ASYNC_DROP_BELOW_LEVEL_DEFAULT,
+				AsyncFlushInterval:  logrusx.LOGGER_CONFIG_ASYNC_FLUSH_INTERVAL_DEFAULT,
+			},
+		},
+		{
+			name: "errors",
+			env: map[string]string{
+				"MYAPP_LOG_USE_JSON":              "maybe",
+				"MYAPP_LOG_LEVEL":                 "loud",
+				"MYAPP_LOG_FILE_MAX_SIZE_MB":      "10MB",
+				"MYAPP_LOG_ASYNC_OVERFLOW_POLICY": "panic",
+				"MYAPP_LOG_TIMESTAMP_FORMAT":      "epoch_hours",
+				"MYAPP_LOG_JSON_FIELD_PRESET":     "splunk",
+				"MYAPP_LOG_FUNC_NAME":             "long",
+				"MYAPP_LOG_CALLER_CACHE_MAX_SIZE": "16k",
+			},
+			wantErrSubs: []string{
+				"MYAPP_LOG_USE_JSON", "MYAPP_LOG_LEVEL", "MYAPP_LOG_FILE_MAX_SIZE_MB", "MYAPP_LOG_ASYNC_OVERFLOW_POLICY",
+				"MYAPP_LOG_TIMESTAMP_FORMAT", "MYAPP_LOG_JSON_FIELD_PRESET", "MYAPP_LOG_FUNC_NAME",
+				"MYAPP_LOG_CALLER_CACHE_MAX_SIZE",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for name, value := range tc.env {
+				t.Setenv(name, value)
+			}
+			cfg, err := logrusx.ApplyLoggerEnv(nil, "MYAPP")
+			if tc.wantErrSubs != nil {
+				if err == nil {
+					t.Fatalf("want error, got nil")
+				}
+				for _, sub := range tc.wantErrSubs {
+					if !strings.Contains(err.Error(), sub) {
+						t.Errorf("error %q: missing %q", err, sub)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.wantCfg, cfg) {
+				t.Fatalf("cfg:\n want: %#v\n  got: %#v", tc.wantCfg, cfg)
+			}
+		})
+	}
+}
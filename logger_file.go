@@ -0,0 +1,161 @@
+// Log file support
+
+package logrusx
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	logrusx_internal "github.com/bgp59/logrusx/internal"
+)
+
+const (
+	LOGGER_ROTATE_INTERVAL_HOURLY = "hourly"
+	LOGGER_ROTATE_INTERVAL_DAILY  = "daily"
+)
+
+// Parse the rotation interval: hourly, daily or a Go duration. An empty
+// interval is parsed as 0, i.e. disabled.
+func ParseRotateInterval(interval string) (time.Duration, error) {
+	switch interval {
+	case "":
+		return 0, nil
+	case LOGGER_ROTATE_INTERVAL_HOURLY:
+		return time.Hour, nil
+	case LOGGER_ROTATE_INTERVAL_DAILY:
+		return 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(interval)
+	if err != nil || d < time.Second {
+		return 0, fmt.Errorf(
+			"invalid rotation interval %q, must be %s, %s or a duration >= 1s",
+			interval, LOGGER_ROTATE_INTERVAL_HOURLY, LOGGER_ROTATE_INTERVAL_DAILY,
+		)
+	}
+	return d, nil
+}
+
+// Whether the log file settings are the same, such that the current log file
+// can be kept:
+func sameLogFileConfig(cfg1, cfg2 *LoggerOutputConfig) bool {
+	return cfg1 != nil && cfg2 != nil &&
+		cfg1.LogFile == cfg2.LogFile &&
+		cfg1.LogFileMaxSizeMB == cfg2.LogFileMaxSizeMB &&
+		cfg1.LogFileMaxBackupNum == cfg2.LogFileMaxBackupNum &&
+		cfg1.LogFileRotateInterval == cfg2.LogFileRotateInterval &&
+		cfg1.LogFileRotateUTC == cfg2.LogFileRotateUTC &&
+		cfg1.LogFileMaxAgeDays == cfg2.LogFileMaxAgeDays &&
+		cfg1.LogFileCompress == cfg2.LogFileCompress &&
+		cfg1.LogFileLocalTime == cfg2.LogFileLocalTime &&
+		cfg1.LogFileExternalRotate == cfg2.LogFileExternalRotate
+}
+
+func newLogFile(cfg *LoggerOutputConfig) (io.WriteCloser, error) {
+	// Create log dir as needed:
+	logDir := path.Dir(cfg.LogFile)
+	_, err := os.Stat(logDir)
+	if err != nil {
+		err = os.MkdirAll(logDir, os.ModePerm)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.LogFileExternalRotate {
+		logFile := &logrusx_internal.ReopenableFile{Filename: cfg.LogFile}
+		// Open it now to report errors early:
+		if _, err := logFile.Reopen(); err != nil {
+			return nil, err
+		}
+		return logFile, nil
+	}
+
+	rotateInterval, err := ParseRotateInterval(cfg.LogFileRotateInterval)
+	if err != nil {
+		return nil, err
+	}
+	if rotateInterval > 0 {
+		logFile := &logrusx_internal.RotatingFile{
+			Filename:   cfg.LogFile,
+			MaxSize:    int64(cfg.LogFileMaxSizeMB) * 1024 * 1024,
+			MaxBackups: cfg.LogFileMaxBackupNum,
+			MaxAge:     time.Duration(cfg.LogFileMaxAgeDays) * 24 * time.Hour,
+			Compress:   cfg.LogFileCompress,
+			Interval:   rotateInterval,
+			UTC:        cfg.LogFileRotateUTC,
+		}
+		// Open it now, rotating the existing file, if any, to report errors
+		// early:
+		if err := logFile.Rotate(); err != nil {
+			return nil, err
+		}
+		return logFile, nil
+	}
+
+	// Check if the log file exists, in which case force rotate it before
+	// the 1st use:
+	_, err = os.Stat(cfg.LogFile)
+	forceRotate := err == nil
+	logFile := &lumberjack.Logger{
+		Filename:   cfg.LogFile,
+		MaxSize:    cfg.LogFileMaxSizeMB,
+		MaxBackups: cfg.LogFileMaxBackupNum,
+		MaxAge:     cfg.LogFileMaxAgeDays,
+		Compress:   cfg.LogFileCompress,
+		LocalTime:  cfg.LogFileLocalTime,
+	}
+	if forceRotate {
+		err := logFile.Rotate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return logFile, nil
+}
+
+// Reopen the log files, if they are externally rotated and they were moved or
+// removed, otherwise this is a no-op.
+func (logger *CollectableLogger) ReopenLogFile() error {
+	logger.cfgMu.Lock()
+	defer logger.cfgMu.Unlock()
+	for _, output := range logger.outputsFormatter.getOutputs() {
+		if logFile, ok := output.logFile.(*logrusx_internal.ReopenableFile); ok {
+			if _, err := logFile.Reopen(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Start/stop listening for the reopen signals, based on whether any of the
+// current log files is externally rotated or not. Should be called under cfgMu.
+func (logger *CollectableLogger) updateReopenOnSignal() {
+	reopenable := false
+	for _, output := range logger.outputsFormatter.getOutputs() {
+		if _, ok := output.logFile.(*logrusx_internal.ReopenableFile); ok {
+			reopenable = true
+			break
+		}
+	}
+	if reopenable && logger.reopenSigCh == nil && len(logFileReopenSignals) > 0 {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, logFileReopenSignals...)
+		go func() {
+			for range sigCh {
+				logger.ReopenLogFile()
+			}
+		}()
+		logger.reopenSigCh = sigCh
+	} else if !reopenable && logger.reopenSigCh != nil {
+		signal.Stop(logger.reopenSigCh)
+		close(logger.reopenSigCh)
+		logger.reopenSigCh = nil
+	}
+}
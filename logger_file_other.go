@@ -0,0 +1,11 @@
+//go:build !unix
+
+package logrusx
+
+import (
+	"os"
+)
+
+// No signal support, externally rotated log files can be reopened only via
+// ReopenLogFile:
+var logFileReopenSignals = []os.Signal{}
@@ -0,0 +1,128 @@
+package logrusx_test
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bgp59/logrusx"
+)
+
+// Wait for the background compression of the backup files and return the
+// directory content:
+func waitForCompressedBackups(t *testing.T, dir string, wantNum int) []string {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		dirEntries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, len(dirEntries))
+		compressedNum := 0
+		for i, dirEntry := range dirEntries {
+			names[i] = dirEntry.Name()
+			if strings.HasSuffix(names[i], ".gz") {
+				compressedNum++
+			}
+		}
+		if compressedNum == wantNum && len(names) == wantNum+1 {
+			return names
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("want %d compressed backup(s), got %q", wantNum, names)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLogFileCompress(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		rotateInterval string
+	}{
+		{"size", ""},
+		{"time", logrusx.LOGGER_ROTATE_INTERVAL_DAILY},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			logFile := path.Join(dir, "app.log")
+			// A pre-existing log file is rotated at 1st use:
+			for i := 0; i < 3; i++ {
+				if err := os.WriteFile(logFile, []byte("old record\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				logger := logrusx.NewCollectableLogger()
+				cfg := logrusx.DefaultLoggerConfig()
+				cfg.LogFile = logFile
+				cfg.LogFileMaxBackupNum = 2
+				cfg.LogFileCompress = true
+				cfg.LogFileMaxAgeDays = 1
+				cfg.LogFileRotateInterval = tc.rotateInterval
+				if err := logger.SetLogger(cfg); err != nil {
+					t.Fatal(err)
+				}
+				logger.Info("new record")
+				logger.SetLogger(&logrusx.LoggerConfig{LogFile: "stderr"})
+				// Allow for distinct backup timestamps:
+				time.Sleep(time.Millisecond)
+			}
+			waitForCompressedBackups(t, dir, 2)
+
+			buf, err := os.ReadFile(logFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(buf), "new record") || strings.Contains(string(buf), "old record") {
+				t.Fatalf("unexpected %s content: %q", logFile, string(buf))
+			}
+		})
+	}
+}
+
+func TestLogFileExternalRotate(t *testing.T) {
+	dir := t.TempDir()
+	logFile := path.Join(dir, "app.log")
+	if err := os.WriteFile(logFile, []byte("old record\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logrusx.NewCollectableLogger()
+	cfg := logrusx.DefaultLoggerConfig()
+	cfg.LogFile = logFile
+	cfg.LogFileExternalRotate = true
+	if err := logger.SetLogger(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer logger.SetLogger(&logrusx.LoggerConfig{LogFile: "stderr"})
+
+	logger.Info("record 1")
+	if err := os.Rename(logFile, logFile+".1"); err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("record 2")
+	if err := logger.ReopenLogFile(); err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("record 3")
+
+	for fileName, want := range map[string][]string{
+		logFile + ".1": {"old record", "record 1", "record 2"},
+		logFile:        {"record 3"},
+	} {
+		buf, err := os.ReadFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(buf)), "\n")
+		if len(lines) != len(want) {
+			t.Fatalf("%s: want %d lines, got %q", fileName, len(want), lines)
+		}
+		for i := range want {
+			if !strings.Contains(lines[i], want[i]) {
+				t.Errorf("%s line# %d: want %q, got %q", fileName, i+1, want[i], lines[i])
+			}
+		}
+	}
+}
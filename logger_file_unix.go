@@ -0,0 +1,11 @@
+//go:build unix
+
+package logrusx
+
+import (
+	"os"
+	"syscall"
+)
+
+// The signals for reopening an externally rotated log file:
+var logFileReopenSignals = []os.Signal{syscall.SIGUSR1, syscall.SIGHUP}
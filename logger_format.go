@@ -0,0 +1,228 @@
+// Record format selection, via a registry of formatter constructors
+
+package logrusx
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	logrusx_internal "github.com/bgp59/logrusx/internal"
+)
+
+const (
+	LOGGER_FORMAT_TEXT   = "text"
+	LOGGER_FORMAT_JSON   = "json"
+	LOGGER_FORMAT_LOGFMT = "logfmt"
+	// Colorized, aligned, for interactive terminals:
+	LOGGER_FORMAT_CONSOLE = "console"
+)
+
+// Timestamp formats, besides them a Go time layout may be used:
+const (
+	LOGGER_TIMESTAMP_RFC3339       = logrusx_internal.TIMESTAMP_RFC3339
+	LOGGER_TIMESTAMP_RFC3339_MILLI = logrusx_internal.TIMESTAMP_RFC3339_MILLI
+	LOGGER_TIMESTAMP_RFC3339_MICRO = logrusx_internal.TIMESTAMP_RFC3339_MICRO
+	LOGGER_TIMESTAMP_RFC3339_NANO  = logrusx_internal.TIMESTAMP_RFC3339_NANO
+	LOGGER_TIMESTAMP_EPOCH         = logrusx_internal.TIMESTAMP_EPOCH
+	LOGGER_TIMESTAMP_EPOCH_MILLI   = logrusx_internal.TIMESTAMP_EPOCH_MILLI
+	LOGGER_TIMESTAMP_EPOCH_MICRO   = logrusx_internal.TIMESTAMP_EPOCH_MICRO
+	LOGGER_TIMESTAMP_EPOCH_NANO    = logrusx_internal.TIMESTAMP_EPOCH_NANO
+)
+
+var loggerTimestampFormats = logrusx_internal.TimestampFormatNames
+
+// JSON field keys presets, see LoggerConfig.JsonFieldPreset:
+const (
+	LOGGER_JSON_FIELD_PRESET_ECS     = logrusx_internal.JSON_FIELD_PRESET_ECS
+	LOGGER_JSON_FIELD_PRESET_GCP     = logrusx_internal.JSON_FIELD_PRESET_GCP
+	LOGGER_JSON_FIELD_PRESET_DATADOG = logrusx_internal.JSON_FIELD_PRESET_DATADOG
+)
+
+// The JSON field keys, based on LoggerConfig.JsonFieldPreset and
+// JsonFieldMap:
+type LoggerJsonFieldKeys = logrusx_internal.JsonFieldKeys
+
+// The field key order for the key=value based formats, based on
+// LoggerConfig.FieldOrder*. Custom formatters should use its Sort method:
+type LoggerFieldKeyOrder = logrusx_internal.LogFieldKeyOrder
+
+// Get the default field order, i.e. the leading and the trailing keys:
+func GetLoggerDefaultFieldOrder() (leading, trailing []string) {
+	return logrusx_internal.DefaultLogFieldKeyOrderLists()
+}
+
+// Get the list of JSON field presets:
+func GetLoggerJsonFieldPresetNames() []string {
+	return logrusx_internal.GetJsonFieldPresetNames()
+}
+
+// The caller prettyfier shared by all the formatters of a logger, it returns
+// the function name and the file:line# info relative to the module root, see
+// AddCallerSrcPathPrefix. It has the signature expected by the logrus
+// formatters:
+type LoggerCallerPrettyfier func(*runtime.Frame) (function string, file string)
+
+// The timestamp formatter, based on the timestamp config, see LoggerConfig. It
+// returns nil if the timestamp is disabled, an int64 for epoch formats and a
+// string otherwise.
+type LoggerTimestampFunc func(time.Time) any
+
+// The options passed to the formatter constructors:
+type LoggerFormatterOptions struct {
+	// Shared by all the formatters of a logger:
+	CallerPrettyfier LoggerCallerPrettyfier
+	Timestamp        LoggerTimestampFunc
+	// For JSON based formats; nil stands for the logrus keys:
+	JsonFieldKeys *LoggerJsonFieldKeys
+	// For key=value based formats:
+	FieldKeyOrder *LoggerFieldKeyOrder
+}
+
+// Formatter constructor, invoked for every output using the format:
+type LoggerFormatterConstructor func(opts *LoggerFormatterOptions) logrus.Formatter
+
+// Formatters that adapt to the output, e.g. using colors only for terminals,
+// should implement this interface; SetOutput is invoked whenever the output
+// changes.
+type LoggerOutputAwareFormatter interface {
+	SetOutput(out io.Writer)
+}
+
+var loggerFormatRegistry = struct {
+	m            sync.Mutex
+	constructors map[string]LoggerFormatterConstructor
+}{
+	constructors: map[string]LoggerFormatterConstructor{
+		LOGGER_FORMAT_TEXT: func(opts *LoggerFormatterOptions) logrus.Formatter {
+			return logrusx_internal.NewTextFormatter(opts.CallerPrettyfier, opts.Timestamp, opts.FieldKeyOrder)
+		},
+		LOGGER_FORMAT_JSON: func(opts *LoggerFormatterOptions) logrus.Formatter {
+			return logrusx_internal.NewJsonFormatter(opts.CallerPrettyfier, opts.Timestamp, opts.JsonFieldKeys)
+		},
+		LOGGER_FORMAT_LOGFMT: func(opts *LoggerFormatterOptions) logrus.Formatter {
+			return logrusx_internal.NewLogfmtFormatter(opts.CallerPrettyfier, opts.Timestamp, opts.FieldKeyOrder)
+		},
+		// The console has its own short timestamp:
+		LOGGER_FORMAT_CONSOLE: func(opts *LoggerFormatterOptions) logrus.Formatter {
+			return logrusx_internal.NewConsoleFormatter(opts.CallerPrettyfier, opts.FieldKeyOrder)
+		},
+	},
+}
+
+// Register a formatter constructor under a format name, to be used in
+// LoggerConfig.Format. An existing registration for the same name, builtin or
+// not, is replaced. It should be called before loading the config, typically
+// from init().
+func RegisterLoggerFormat(format string, constructor LoggerFormatterConstructor) {
+	loggerFormatRegistry.m.Lock()
+	defer loggerFormatRegistry.m.Unlock()
+	loggerFormatRegistry.constructors[format] = constructor
+}
+
+// Remove a format registration, builtin or not, e.g. for test cleanup:
+func UnregisterLoggerFormat(format string) {
+	loggerFormatRegistry.m.Lock()
+	defer loggerFormatRegistry.m.Unlock()
+	delete(loggerFormatRegistry.constructors, format)
+}
+
+// Get the list of registered format names:
+func GetLoggerFormatNames() []string {
+	loggerFormatRegistry.m.Lock()
+	defer loggerFormatRegistry.m.Unlock()
+	formats := make([]string, 0, len(loggerFormatRegistry.constructors))
+	for format := range loggerFormatRegistry.constructors {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+func getLoggerFormatterConstructor(format string) LoggerFormatterConstructor {
+	loggerFormatRegistry.m.Lock()
+	defer loggerFormatRegistry.m.Unlock()
+	return loggerFormatRegistry.constructors[format]
+}
+
+func isValidFormat(format string) bool {
+	return getLoggerFormatterConstructor(format) != nil
+}
+
+// Build the formatter for an output config. The format, if not empty,
+// supersedes UseJson, which is kept for backward compatibility.
+func (logger *CollectableLogger) newFormatter(cfg *LoggerOutputConfig) (logrus.Formatter, error) {
+	format := cfg.Format
+	if format == "" {
+		format = LOGGER_FORMAT_TEXT
+		if cfg.UseJson {
+			format = LOGGER_FORMAT_JSON
+		}
+	}
+	constructor := getLoggerFormatterConstructor(format)
+	if constructor == nil {
+		return nil, fmt.Errorf("invalid format %q, must be one of %v", format, GetLoggerFormatNames())
+	}
+	timestampFormatter, err := logrusx_internal.NewTimestampFormatter(
+		cfg.TimestampFormat, cfg.TimestampUTC, cfg.DisableTimestamp,
+	)
+	if err != nil {
+		return nil, err
+	}
+	jsonFieldKeys, err := logrusx_internal.NewJsonFieldKeys(cfg.JsonFieldPreset, cfg.JsonFieldMap)
+	if err != nil {
+		return nil, err
+	}
+	return constructor(&LoggerFormatterOptions{
+		CallerPrettyfier: logger.prettyfier.Pretiffy,
+		Timestamp:        timestampFormatter.Format,
+		JsonFieldKeys:    jsonFieldKeys,
+		FieldKeyOrder:    newFieldKeyOrder(cfg),
+	}), nil
+}
+
+// Parse a comma separated list of field keys, as used by the args and the
+// environment; an empty list is not nil, i.e. it does not stand for the
+// default:
+func parseFieldKeyList(value string) []string {
+	keys := make([]string, 0)
+	for _, key := range strings.Split(value, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func newFieldKeyOrder(cfg *LoggerOutputConfig) *LoggerFieldKeyOrder {
+	leading, trailing := GetLoggerDefaultFieldOrder()
+	if cfg.FieldOrderLeading != nil {
+		leading = cfg.FieldOrderLeading
+	}
+	if cfg.FieldOrderTrailing != nil {
+		trailing = cfg.FieldOrderTrailing
+	}
+	keyOrder := logrusx_internal.NewLogFieldKeyOrder(leading, trailing)
+	if cfg.FieldOrderUnsorted {
+		keyOrder = keyOrder.Unsorted()
+	}
+	return keyOrder
+}
+
+// Validate the JSON field preset and map:
+func validateJsonFields(preset string, fieldMap map[string]string) error {
+	_, err := logrusx_internal.NewJsonFieldKeys(preset, fieldMap)
+	return err
+}
+
+// Validate the timestamp format:
+func validateTimestampFormat(format string) error {
+	_, err := logrusx_internal.NewTimestampFormatter(format, false, false)
+	return err
+}
@@ -0,0 +1,247 @@
+package logrusx_test
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/bgp59/logrusx"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLoggerFormat(t *testing.T) {
+	for _, tc := range []struct {
+		format  string
+		useJson bool
+		wantRe  string
+	}{
+		{"", false, `^time=\S+ level=info file=\S+ k=v msg=record$`},
+		{"", true, `^\{.*"msg":"record".*\}$`},
+		{"text", true, `^time="\S+" level=info file="\S+" k=v msg=record$`},
+		{"json", false, `^\{.*"msg":"record".*\}$`},
+		{"logfmt", true, `^time=\S+ level=info file=\S+ k=v msg=record$`},
+	} {
+		logger := logrusx.NewCollectableLogger()
+		cfg := logrusx.DefaultLoggerConfig()
+		cfg.Format = tc.format
+		cfg.UseJson = tc.useJson
+		if err := logger.SetLogger(cfg); err != nil {
+			t.Fatal(err)
+		}
+		buf := &bytes.Buffer{}
+		logger.SetOutput(buf)
+		logger.WithField("k", "v").Info("record")
+		if got := strings.TrimSpace(buf.String()); !regexp.MustCompile(tc.wantRe).MatchString(got) {
+			t.Errorf("format=%q, use_json=%v: want match %q, got %q", tc.format, tc.useJson, tc.wantRe, got)
+		}
+	}
+}
+
+// A custom format, for testing the registry:
+type testFormatter struct {
+	callerPrettyfier logrusx.LoggerCallerPrettyfier
+}
+
+func (f *testFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	_, file := f.callerPrettyfier(entry.Caller)
+	return []byte(fmt.Sprintf("TEST %s %s %s\n", entry.Level, file, entry.Message)), nil
+}
+
+func TestRegisterLoggerFormat(t *testing.T) {
+	logrusx.RegisterLoggerFormat("test", func(opts *logrusx.LoggerFormatterOptions) logrus.Formatter {
+		return &testFormatter{opts.CallerPrettyfier}
+	})
+	t.Cleanup(func() { logrusx.UnregisterLoggerFormat("test") })
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	loggerArgs := logrusx.NewLoggerArgs(fs, "")
+	if err := fs.Parse([]string{"--log-format=test"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := logrusx.DefaultLoggerConfig()
+	loggerArgs.ApplySet(cfg)
+	if cfg.Format != "test" {
+		t.Fatalf("format: want %q, got %q", "test", cfg.Format)
+	}
+
+	logger := logrusx.NewCollectableLogger()
+	if err := logger.SetLogger(cfg); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	logger.SetOutput(buf)
+	logger.Warn("record")
+	wantRe := `^TEST warning \S*logger_format_test.go:\d+ record$`
+	if got := strings.TrimSpace(buf.String()); !regexp.MustCompile(wantRe).MatchString(got) {
+		t.Errorf("want match %q, got %q", wantRe, got)
+	}
+
+	cfg.Format = "no-such-format"
+	if err := logger.SetLogger(cfg); err == nil || !strings.Contains(err.Error(), "test") {
+		t.Errorf("want error listing the registered formats, got %v", err)
+	}
+
+	logrusx.UnregisterLoggerFormat("test")
+	for _, format := range logrusx.GetLoggerFormatNames() {
+		if format == "test" {
+			t.Errorf("unregistered format %q still listed", format)
+		}
+	}
+}
+
+func TestLoggerConsoleFormat(t *testing.T) {
+	for _, tc := range []struct {
+		forceColor string
+		wantColors bool
+	}{
+		{"", false},
+		{"1", true},
+	} {
+		t.Setenv("NO_COLOR", "")
+		t.Setenv("FORCE_COLOR", tc.forceColor)
+		logger := logrusx.NewCollectableLogger()
+		cfg := logrusx.DefaultLoggerConfig()
+		cfg.Format = logrusx.LOGGER_FORMAT_CONSOLE
+		if err := logger.SetLogger(cfg); err != nil {
+			t.Fatal(err)
+		}
+		buf := &bytes.Buffer{}
+		logger.SetOutput(buf)
+		logger.Info("record")
+		got := buf.String()
+		if gotColors := strings.Contains(got, "\x1b["); gotColors != tc.wantColors {
+			t.Errorf("FORCE_COLOR=%q: want colors %v, got %q", tc.forceColor, tc.wantColors, got)
+		}
+		if !strings.Contains(got, "INFO") || !strings.Contains(got, "record") {
+			t.Errorf("FORCE_COLOR=%q: unexpected %q", tc.forceColor, got)
+		}
+	}
+}
+
+func TestLoggerTimestamp(t *testing.T) {
+	for _, tc := range []struct {
+		format           string
+		timestampFormat  string
+		timestampUTC     bool
+		disableTimestamp bool
+		wantRe           string
+	}{
+		{"json", logrusx.LOGGER_TIMESTAMP_EPOCH_MILLI, false, false, `^\{.*"time":\d{13}[,}]`},
+		{"json", logrusx.LOGGER_TIMESTAMP_RFC3339_NANO, true, false, `"time":"\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{9}Z"`},
+		{"json", "", false, true, `^\{("(level|file|msg)":"[^"]*",?)+\}$`},
+		{"text", logrusx.LOGGER_TIMESTAMP_EPOCH, false, false, `^time=\d{10} level=info `},
+		{"text", logrusx.LOGGER_TIMESTAMP_RFC3339_MILLI, true, false, `^time="\S+\.\d{3}Z" level=info `},
+		{"text", "", false, true, `^level=info `},
+		{"logfmt", logrusx.LOGGER_TIMESTAMP_EPOCH_NANO, false, false, `^time=\d{19} level=info `},
+		{"logfmt", "", false, true, `^level=info `},
+	} {
+		logger := logrusx.NewCollectableLogger()
+		cfg := logrusx.DefaultLoggerConfig()
+		cfg.Format = tc.format
+		cfg.TimestampFormat = tc.timestampFormat
+		cfg.TimestampUTC = tc.timestampUTC
+		cfg.DisableTimestamp = tc.disableTimestamp
+		if err := logger.SetLogger(cfg); err != nil {
+			t.Fatal(err)
+		}
+		buf := &bytes.Buffer{}
+		logger.SetOutput(buf)
+		logger.Info("record")
+		if got := strings.TrimSpace(buf.String()); !regexp.MustCompile(tc.wantRe).MatchString(got) {
+			t.Errorf(
+				"format=%q, timestamp_format=%q, timestamp_utc=%v, disable_timestamp=%v: want match %q, got %q",
+				tc.format, tc.timestampFormat, tc.timestampUTC, tc.disableTimestamp, tc.wantRe, got,
+			)
+		}
+	}
+
+	cfg := logrusx.DefaultLoggerConfig()
+	cfg.TimestampFormat = "epoch_hours"
+	if err := logrusx.NewCollectableLogger().SetLogger(cfg); err == nil || !strings.Contains(err.Error(), "timestamp_format") {
+		t.Errorf("want timestamp_format error, got %v", err)
+	}
+}
+
+func TestLoggerJsonFields(t *testing.T) {
+	for _, tc := range []struct {
+		preset   string
+		fieldMap map[string]string
+		wantRe   string
+	}{
+		{"", nil, `^\{"comp":"comp","file":"\S*logger_format_test.go:\d+","k":"v","level":"info","msg":"record","time":"[^"]+"\}$`},
+		{
+			logrusx.LOGGER_JSON_FIELD_PRESET_GCP, nil,
+			`^\{"component":"comp","k":"v","logging.googleapis.com/sourceLocation":\{"file":"\S*logger_format_test.go","line":"\d+"\},"message":"record","severity":"INFO","time":"[^"]+"\}$`,
+		},
+		{
+			logrusx.LOGGER_JSON_FIELD_PRESET_ECS, map[string]string{"comp": "service.name"},
+			`^\{"@timestamp":"[^"]+","k":"v","log.level":"info","log.origin.file.line":\d+,"log.origin.file.name":"\S*logger_format_test.go","message":"record","service.name":"comp"\}$`,
+		},
+	} {
+		logger := logrusx.NewCollectableLogger()
+		cfg := logrusx.DefaultLoggerConfig()
+		cfg.Format = logrusx.LOGGER_FORMAT_JSON
+		cfg.JsonFieldPreset = tc.preset
+		cfg.JsonFieldMap = tc.fieldMap
+		if err := logger.SetLogger(cfg); err != nil {
+			t.Fatal(err)
+		}
+		buf := &bytes.Buffer{}
+		logger.SetOutput(buf)
+		logger.NewCompLogger("comp").WithField("k", "v").Info("record")
+		if got := strings.TrimSpace(buf.String()); !regexp.MustCompile(tc.wantRe).MatchString(got) {
+			t.Errorf("preset=%q, field_map=%v: want match %q, got %q", tc.preset, tc.fieldMap, tc.wantRe, got)
+		}
+	}
+
+	cfg := logrusx.DefaultLoggerConfig()
+	cfg.JsonFieldMap = map[string]string{"severity": "level"}
+	if err := logrusx.NewCollectableLogger().SetLogger(cfg); err == nil || !strings.Contains(err.Error(), "json_field_map.severity") {
+		t.Errorf("want json_field_map.severity error, got %v", err)
+	}
+}
+
+func TestLoggerFieldOrder(t *testing.T) {
+	for _, tc := range []struct {
+		format   string
+		leading  []string
+		trailing []string
+		wantRe   string
+	}{
+		{"text", nil, nil, `^time=\S+ level=info comp=comp file=\S+ a=1 request_id=r z=2 msg=record$`},
+		{"text", []string{"request_id", "level"}, []string{"msg", "time"}, `^request_id=r level=info a=1 comp=comp file=\S+ z=2 msg=record time=\S+$`},
+		{"logfmt", []string{"request_id"}, []string{}, `^request_id=r a=1 comp=comp file=\S+ level=info msg=record time=\S+ z=2$`},
+		{"console", []string{"request_id"}, nil, ` record +\S+ request_id=r a=1 z=2$`},
+	} {
+		// Build 2 loggers, to verify that they do not interfere:
+		loggers := make([]*logrusx.CollectableLogger, 2)
+		bufs := make([]*bytes.Buffer, 2)
+		for i := range loggers {
+			loggers[i] = logrusx.NewCollectableLogger()
+			cfg := logrusx.DefaultLoggerConfig()
+			cfg.Format = tc.format
+			if i == 0 {
+				cfg.FieldOrderLeading = tc.leading
+				cfg.FieldOrderTrailing = tc.trailing
+			}
+			if err := loggers[i].SetLogger(cfg); err != nil {
+				t.Fatal(err)
+			}
+			bufs[i] = &bytes.Buffer{}
+			loggers[i].SetOutput(bufs[i])
+			loggers[i].NewCompLogger("comp").WithFields(logrus.Fields{"z": 2, "request_id": "r", "a": 1}).Info("record")
+		}
+		if got := strings.TrimSpace(bufs[0].String()); !regexp.MustCompile(tc.wantRe).MatchString(got) {
+			t.Errorf("format=%q, leading=%q, trailing=%q: want match %q, got %q", tc.format, tc.leading, tc.trailing, tc.wantRe, got)
+		}
+		if tc.format == "text" {
+			wantRe := `^time=\S+ level=info comp=comp file=\S+ a=1 request_id=r z=2 msg=record$`
+			if got := strings.TrimSpace(bufs[1].String()); !regexp.MustCompile(wantRe).MatchString(got) {
+				t.Errorf("format=%q, default order: want match %q, got %q", tc.format, wantRe, got)
+			}
+		}
+	}
+}
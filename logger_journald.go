@@ -0,0 +1,91 @@
+// systemd-journald output support
+
+package logrusx
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	logrusx_internal "github.com/bgp59/logrusx/internal"
+)
+
+// The journald destination is an URL, used in lieu of the log file:
+//
+//	journald://[/path][?app_name=NAME]
+//
+// where the path is the journald socket, default
+// /run/systemd/journal/socket, and the app name is used for
+// SYSLOG_IDENTIFIER, default the program name. Each logrus field is sent as a
+// journal field, w/ the name converted to upper case, e.g. comp -> COMP,
+// file -> FILE. If the socket does not exist, e.g. the program does not run
+// under systemd, the output falls back to stderr, using the output format.
+const (
+	LOGGER_JOURNALD_SCHEME = "journald"
+)
+
+type journaldDestination struct {
+	socketPath string
+	appName    string
+}
+
+// Whether the log file is in fact a journald destination:
+func isJournaldDestination(logFile string) bool {
+	return strings.HasPrefix(logFile, LOGGER_JOURNALD_SCHEME+"://")
+}
+
+func parseJournaldDestination(dest string) (*journaldDestination, error) {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host != "" {
+		return nil, fmt.Errorf("%q: unexpected host %q", dest, u.Host)
+	}
+	query := u.Query()
+	for key := range query {
+		if key != "app_name" {
+			return nil, fmt.Errorf("%q: invalid query parameter %q", dest, key)
+		}
+	}
+
+	jd := &journaldDestination{
+		socketPath: u.Path,
+		appName:    query.Get("app_name"),
+	}
+	if jd.socketPath == "" {
+		jd.socketPath = logrusx_internal.JOURNALD_SOCKET_PATH
+	}
+	if jd.appName == "" {
+		jd.appName = path.Base(os.Args[0])
+	}
+	return jd, nil
+}
+
+// Whether journald can be used for the destination, i.e. its socket exists:
+func isJournaldAvailable(dest string) bool {
+	jd, err := parseJournaldDestination(dest)
+	if err != nil {
+		return false
+	}
+	fi, err := os.Stat(jd.socketPath)
+	return err == nil && fi.Mode()&os.ModeSocket != 0
+}
+
+func newJournaldWriter(dest string) (*logrusx_internal.JournaldWriter, error) {
+	jd, err := parseJournaldDestination(dest)
+	if err != nil {
+		return nil, err
+	}
+	return &logrusx_internal.JournaldWriter{SocketPath: jd.socketPath}, nil
+}
+
+func (logger *CollectableLogger) newJournaldFormatter(dest string, cfg *LoggerOutputConfig) (*logrusx_internal.JournaldFormatter, error) {
+	jd, err := parseJournaldDestination(dest)
+	if err != nil {
+		return nil, err
+	}
+	return logrusx_internal.NewJournaldFormatter(jd.appName, logger.prettyfier, newFieldKeyOrder(cfg)), nil
+}
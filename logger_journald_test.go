@@ -0,0 +1,173 @@
+package logrusx_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bgp59/logrusx"
+)
+
+// Parse a native protocol datagram into fields:
+func parseJournaldFields(t *testing.T, buf []byte) map[string]string {
+	fields := make(map[string]string)
+	for len(buf) > 0 {
+		i := bytes.IndexAny(buf, "=\n")
+		if i < 0 {
+			t.Fatalf("invalid journal entry: %q", buf)
+		}
+		name := string(buf[:i])
+		if buf[i] == '=' {
+			buf = buf[i+1:]
+			j := bytes.IndexByte(buf, '\n')
+			if j < 0 {
+				t.Fatalf("invalid journal entry: %q", buf)
+			}
+			fields[name], buf = string(buf[:j]), buf[j+1:]
+		} else {
+			buf = buf[i+1:]
+			n := int(binary.LittleEndian.Uint64(buf[:8]))
+			fields[name], buf = string(buf[8:8+n]), buf[8+n+1:]
+		}
+	}
+	return fields
+}
+
+func TestJournaldOutput(t *testing.T) {
+	sockPath := path.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenPacket("unixgram", sockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	logger := logrusx.NewCollectableLogger()
+	cfg := logrusx.DefaultLoggerConfig()
+	cfg.LogFile = "journald://" + sockPath + "?app_name=test-app"
+	if err := logger.SetLogger(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer logger.SetLogger(&logrusx.LoggerConfig{LogFile: "stderr"})
+	logger.NewCompLogger("comp").WithField("req-id", 13).Warn("line 1\nline 2")
+
+	buf := make([]byte, 65536)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := parseJournaldFields(t, buf[:n])
+	for name, want := range map[string]string{
+		"MESSAGE":           "line 1\nline 2",
+		"PRIORITY":          "4",
+		"SYSLOG_IDENTIFIER": "test-app",
+		"COMP":              "comp",
+		"REQ_ID":            "13",
+	} {
+		if got := fields[name]; got != want {
+			t.Errorf("%s: want %q, got %q", name, want, got)
+		}
+	}
+	if !strings.Contains(fields["FILE"], "logger_journald_test.go:") {
+		t.Errorf("FILE: want logger_journald_test.go:LINE#, got %q", fields["FILE"])
+	}
+}
+
+func TestJournaldFuncName(t *testing.T) {
+	sockPath := path.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenPacket("unixgram", sockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	logger := logrusx.NewCollectableLogger()
+	cfg := logrusx.DefaultLoggerConfig()
+	cfg.LogFile = "journald://" + sockPath
+	cfg.FuncName = logrusx.LOGGER_FUNC_NAME_SHORT
+	if err := logger.SetLogger(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer logger.SetLogger(&logrusx.LoggerConfig{LogFile: "stderr"})
+	logger.Info("info record")
+
+	buf := make([]byte, 65536)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "logrusx_test.TestJournaldFuncName"
+	if got := parseJournaldFields(t, buf[:n])["FUNC"]; got != want {
+		t.Errorf("FUNC: want %q, got %q", want, got)
+	}
+}
+
+func TestJournaldFieldOrder(t *testing.T) {
+	sockPath := path.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenPacket("unixgram", sockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	logger := logrusx.NewCollectableLogger()
+	cfg := logrusx.DefaultLoggerConfig()
+	cfg.LogFile = "journald://" + sockPath
+	cfg.FieldOrderLeading = []string{"z"}
+	cfg.FieldOrderTrailing = []string{"comp"}
+	if err := logger.SetLogger(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer logger.SetLogger(&logrusx.LoggerConfig{LogFile: "stderr"})
+	logger.NewCompLogger("comp").WithField("a", 1).WithField("z", 2).Info("info record")
+
+	buf := make([]byte, 65536)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := -1
+	for _, field := range []string{"\nZ=2\n", "\nA=1\n", "\nCOMP=comp\n"} {
+		i := bytes.Index(buf[:n], []byte(field))
+		if i <= prev {
+			t.Fatalf("want fields in order Z, A, COMP, got %q", buf[:n])
+		}
+		prev = i
+	}
+}
+
+func TestJournaldFallbackToStderr(t *testing.T) {
+	dir := t.TempDir()
+	stderrFile, err := os.Create(path.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderrFile.Close()
+	savedStderr := os.Stderr
+	os.Stderr = stderrFile
+	defer func() { os.Stderr = savedStderr }()
+
+	logger := logrusx.NewCollectableLogger()
+	cfg := logrusx.DefaultLoggerConfig()
+	cfg.LogFile = "journald://" + path.Join(dir, "no-such.sock")
+	if err := logger.SetLogger(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer logger.SetLogger(&logrusx.LoggerConfig{LogFile: "stdout"})
+	logger.Info("fallback record")
+
+	buf, err := os.ReadFile(stderrFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(buf), "fallback record") {
+		t.Errorf("stderr: want %q, got %q", "fallback record", string(buf))
+	}
+}
@@ -0,0 +1,392 @@
+// Multiple simultaneous outputs, each w/ its own level threshold and format
+
+package logrusx
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	logrusx_internal "github.com/bgp59/logrusx/internal"
+)
+
+// Output config, for LoggerConfig.Outputs. The fields have the same meaning as
+// their LoggerConfig namesakes, except for the level which is a threshold.
+type LoggerOutputConfig struct {
+	// Level threshold name; records less severe than this level are not
+	// written to the output. Leave empty for no threshold, i.e. the output
+	// will get all the records enabled by the logger level:
+	Level string `yaml:"level" json:"level"`
+	// Whether to structure the logged record in JSON:
+	UseJson bool `yaml:"use_json" json:"use_json"`
+	// The record format, see LoggerConfig:
+	Format string `yaml:"format" json:"format"`
+	// Timestamp settings, see LoggerConfig:
+	TimestampFormat  string `yaml:"timestamp_format" json:"timestamp_format"`
+	TimestampUTC     bool   `yaml:"timestamp_utc" json:"timestamp_utc"`
+	DisableTimestamp bool   `yaml:"disable_timestamp" json:"disable_timestamp"`
+	// JSON field keys, see LoggerConfig:
+	JsonFieldPreset string            `yaml:"json_field_preset" json:"json_field_preset"`
+	JsonFieldMap    map[string]string `yaml:"json_field_map" json:"json_field_map"`
+	// Field order, see LoggerConfig:
+	FieldOrderLeading  []string `yaml:"field_order_leading" json:"field_order_leading"`
+	FieldOrderTrailing []string `yaml:"field_order_trailing" json:"field_order_trailing"`
+	FieldOrderUnsorted bool     `yaml:"field_order_unsorted" json:"field_order_unsorted"`
+	// Log file path, stderr, stdout, syslog or journald destination; if empty,
+	// stderr:
+	LogFile string `yaml:"log_file" json:"log_file"`
+	// Log file rotation, see LoggerConfig:
+	LogFileMaxSizeMB      int    `yaml:"log_file_max_size_mb" json:"log_file_max_size_mb"`
+	LogFileMaxBackupNum   int    `yaml:"log_file_max_backup_num" json:"log_file_max_backup_num"`
+	LogFileRotateInterval string `yaml:"log_file_rotate_interval" json:"log_file_rotate_interval"`
+	LogFileRotateUTC      bool   `yaml:"log_file_rotate_utc" json:"log_file_rotate_utc"`
+	LogFileMaxAgeDays     int    `yaml:"log_file_max_age_days" json:"log_file_max_age_days"`
+	LogFileCompress       bool   `yaml:"log_file_compress" json:"log_file_compress"`
+	LogFileLocalTime      bool   `yaml:"log_file_local_time" json:"log_file_local_time"`
+	LogFileExternalRotate bool   `yaml:"log_file_external_rotate" json:"log_file_external_rotate"`
+}
+
+func DefaultLoggerOutputConfig() *LoggerOutputConfig {
+	fieldOrderLeading, fieldOrderTrailing := GetLoggerDefaultFieldOrder()
+	return &LoggerOutputConfig{
+		UseJson:               LOGGER_CONFIG_USE_JSON_DEFAULT,
+		Format:                LOGGER_CONFIG_FORMAT_DEFAULT,
+		TimestampFormat:       LOGGER_CONFIG_TIMESTAMP_FORMAT_DEFAULT,
+		TimestampUTC:          LOGGER_CONFIG_TIMESTAMP_UTC_DEFAULT,
+		DisableTimestamp:      LOGGER_CONFIG_DISABLE_TIMESTAMP_DEFAULT,
+		JsonFieldPreset:       LOGGER_CONFIG_JSON_FIELD_PRESET_DEFAULT,
+		FieldOrderUnsorted:    LOGGER_CONFIG_FIELD_ORDER_UNSORTED_DEFAULT,
+		FieldOrderLeading:     fieldOrderLeading,
+		FieldOrderTrailing:    fieldOrderTrailing,
+		LogFile:               LOGGER_CONFIG_LOG_FILE_DEFAULT,
+		LogFileMaxSizeMB:      LOGGER_CONFIG_LOG_FILE_MAX_SIZE_MB_DEFAULT,
+		LogFileMaxBackupNum:   LOGGER_CONFIG_LOG_FILE_MAX_BACKUP_NUM_DEFAULT,
+		LogFileRotateInterval: LOGGER_CONFIG_LOG_FILE_ROTATE_INTERVAL_DEFAULT,
+		LogFileRotateUTC:      LOGGER_CONFIG_LOG_FILE_ROTATE_UTC_DEFAULT,
+		LogFileMaxAgeDays:     LOGGER_CONFIG_LOG_FILE_MAX_AGE_DAYS_DEFAULT,
+		LogFileCompress:       LOGGER_CONFIG_LOG_FILE_COMPRESS_DEFAULT,
+		LogFileLocalTime:      LOGGER_CONFIG_LOG_FILE_LOCAL_TIME_DEFAULT,
+		LogFileExternalRotate: LOGGER_CONFIG_LOG_FILE_EXTERNAL_ROTATE_DEFAULT,
+	}
+}
+
+// Start each output from the defaults when loading from YAML. Since the
+// decoding is delegated to the node, the check for unknown keys has to be done
+// here.
+func (cfg *LoggerOutputConfig) UnmarshalYAML(node *yaml.Node) error {
+	if err := checkYAMLKnownFields(node, reflect.TypeOf(*cfg)); err != nil {
+		return err
+	}
+	type plainLoggerOutputConfig LoggerOutputConfig
+	*cfg = *DefaultLoggerOutputConfig()
+	return node.Decode((*plainLoggerOutputConfig)(cfg))
+}
+
+func checkYAMLKnownFields(node *yaml.Node, structType reflect.Type) error {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	knownFields := make(map[string]bool)
+	for i := 0; i < structType.NumField(); i++ {
+		knownFields[strings.Split(structType.Field(i).Tag.Get("yaml"), ",")[0]] = true
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		if key := node.Content[i]; !knownFields[key.Value] {
+			return fmt.Errorf("line %d: field %s not found in type %s", key.Line, key.Value, structType)
+		}
+	}
+	return nil
+}
+
+// The output config derived from the main config, when there are no explicit
+// outputs:
+func (cfg *LoggerConfig) primaryOutputConfig() *LoggerOutputConfig {
+	return &LoggerOutputConfig{
+		UseJson:               cfg.UseJson,
+		Format:                cfg.Format,
+		TimestampFormat:       cfg.TimestampFormat,
+		TimestampUTC:          cfg.TimestampUTC,
+		DisableTimestamp:      cfg.DisableTimestamp,
+		JsonFieldPreset:       cfg.JsonFieldPreset,
+		JsonFieldMap:          cfg.JsonFieldMap,
+		FieldOrderLeading:     cfg.FieldOrderLeading,
+		FieldOrderTrailing:    cfg.FieldOrderTrailing,
+		FieldOrderUnsorted:    cfg.FieldOrderUnsorted,
+		LogFile:               cfg.LogFile,
+		LogFileMaxSizeMB:      cfg.LogFileMaxSizeMB,
+		LogFileMaxBackupNum:   cfg.LogFileMaxBackupNum,
+		LogFileRotateInterval: cfg.LogFileRotateInterval,
+		LogFileRotateUTC:      cfg.LogFileRotateUTC,
+		LogFileMaxAgeDays:     cfg.LogFileMaxAgeDays,
+		LogFileCompress:       cfg.LogFileCompress,
+		LogFileLocalTime:      cfg.LogFileLocalTime,
+		LogFileExternalRotate: cfg.LogFileExternalRotate,
+	}
+}
+
+// Each output writes via its own writer, since the records may come from
+// different loggers (root and components), each w/ its own lock, the writes
+// have to be serialized here:
+type syncWriter struct {
+	m   sync.Mutex
+	out io.Writer
+}
+
+func (w *syncWriter) Write(buf []byte) (int, error) {
+	if len(buf) == 0 {
+		// The record was not meant for this output:
+		return 0, nil
+	}
+	w.m.Lock()
+	defer w.m.Unlock()
+	return w.out.Write(buf)
+}
+
+func (w *syncWriter) getOutput() io.Writer {
+	w.m.Lock()
+	defer w.m.Unlock()
+	return w.out
+}
+
+func (w *syncWriter) setOutput(out io.Writer) {
+	w.m.Lock()
+	defer w.m.Unlock()
+	w.out = out
+}
+
+type loggerOutput struct {
+	out       *syncWriter
+	formatter logrus.Formatter
+	level     logrus.Level
+	// The log file or the syslog/journald writer, if any, and the config
+	// used for opening it:
+	logFile io.WriteCloser
+	cfg     *LoggerOutputConfig
+	// The async writer, in async mode, it writes to out:
+	async *logrusx_internal.AsyncWriter
+}
+
+func (output *loggerOutput) write(level logrus.Level, buf []byte) error {
+	if output.async != nil {
+		return output.async.WriteLevel(level, buf)
+	}
+	_, err := output.out.Write(buf)
+	return err
+}
+
+// The formatter shared by all the loggers, root and components; it dispatches
+// the record to all the outputs. The 1st output is the primary one: its
+// formatted record is returned for being written to logrus.Logger.Out, which
+// is the primary output writer. The records for the other outputs are written
+// directly. The list of outputs is replaced atomically, in its entirety, upon
+// changes. In async mode the formatted records are queued for all the
+// outputs, including the primary one, and the actual writing is deferred. The
+// caller is adjusted for the logging helpers before formatting, see Helper.
+type outputsFormatter struct {
+	outputs       atomic.Pointer[[]*loggerOutput]
+	callerHelpers *callerHelpers
+}
+
+func (f *outputsFormatter) getOutputs() []*loggerOutput {
+	return *f.outputs.Load()
+}
+
+func (f *outputsFormatter) setOutputs(outputs []*loggerOutput) {
+	f.outputs.Store(&outputs)
+}
+
+func (f *outputsFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	f.callerHelpers.adjustCaller(entry)
+	outputs := f.getOutputs()
+	for _, output := range outputs[1:] {
+		if entry.Level > output.level {
+			continue
+		}
+		// The formatters use the entry buffer, if set, w/o resetting it, so
+		// the secondary outputs should use their own:
+		secondaryEntry := *entry
+		secondaryEntry.Buffer = nil
+		buf, err := output.formatter.Format(&secondaryEntry)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to format log record, %v\n", err)
+			continue
+		}
+		if err := output.write(entry.Level, buf); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+		}
+	}
+	primary := outputs[0]
+	if entry.Level > primary.level {
+		return nil, nil
+	}
+	if primary.async == nil {
+		return primary.formatter.Format(entry)
+	}
+	// Async mode, the record is queued instead of being returned for writing:
+	buf, err := primary.formatter.Format(entry)
+	if err == nil {
+		err = primary.async.WriteLevel(entry.Level, buf)
+	}
+	return nil, err
+}
+
+// Build the outputs for a config. The current outputs are used for reusing
+// log files w/ the same config. Return the new outputs, the underlying output
+// for the primary one (nil to keep the current one) and the log files that are
+// no longer in use. Should be called under cfgMu.
+func (logger *CollectableLogger) newOutputs(cfg *LoggerConfig) (
+	outputs []*loggerOutput,
+	primaryOut io.Writer,
+	unusedLogFiles []io.Closer,
+	err error,
+) {
+	currentOutputs := logger.outputsFormatter.getOutputs()
+	reusedLogFiles := make(map[io.WriteCloser]bool)
+
+	outputCfgs := make([]*LoggerOutputConfig, 0, len(cfg.Outputs)+1)
+	singleOutput := len(cfg.Outputs) == 0
+	if singleOutput {
+		outputCfgs = append(outputCfgs, cfg.primaryOutputConfig())
+	} else {
+		for _, outputCfg := range cfg.Outputs {
+			outputCfgs = append(outputCfgs, &outputCfg)
+		}
+	}
+
+	wrapErr := func(i int, err error) error {
+		if !singleOutput {
+			err = fmt.Errorf("outputs[%d]: %w", i, err)
+		}
+		return err
+	}
+
+	// Build the formatters and the levels for all the outputs before opening
+	// any of the log files, since the latter may force a rotation:
+	outputs = make([]*loggerOutput, len(outputCfgs))
+	for i, outputCfg := range outputCfgs {
+		output := &loggerOutput{
+			level: logrus.TraceLevel,
+			cfg:   outputCfg,
+		}
+		// The destination, which is the current one if kept, see below:
+		dest := outputCfg.LogFile
+		if singleOutput && dest == "" && currentOutputs[0].cfg != nil {
+			dest = currentOutputs[0].cfg.LogFile
+		}
+		switch {
+		case isSyslogDestination(dest):
+			// Syslog and journald have their own message format:
+			output.formatter, err = logger.newSyslogFormatter(dest, outputCfg)
+		case isJournaldDestination(dest) && isJournaldAvailable(dest):
+			output.formatter, err = logger.newJournaldFormatter(dest, outputCfg)
+		default:
+			// W/ the journald destinations falling back to stderr, using the
+			// regular format, when not running under systemd:
+			output.formatter, err = logger.newFormatter(outputCfg)
+		}
+		if err == nil && outputCfg.Level != "" {
+			output.level, err = logrus.ParseLevel(outputCfg.Level)
+		}
+		if err != nil {
+			return nil, nil, nil, wrapErr(i, err)
+		}
+		outputs[i] = output
+	}
+
+	newLogFiles := make([]io.Closer, 0)
+	for i, output := range outputs {
+		outputCfg := output.cfg
+		var out io.Writer
+		switch outputCfg.LogFile {
+		case "stderr":
+			out = os.Stderr
+		case "stdout":
+			out = os.Stdout
+		case "":
+			if singleOutput {
+				// Keep the current primary output, whatever it may be:
+				output.logFile, output.cfg = currentOutputs[0].logFile, currentOutputs[0].cfg
+				if output.logFile != nil {
+					reusedLogFiles[output.logFile] = true
+				}
+			} else {
+				out = os.Stderr
+			}
+		default:
+			if isJournaldDestination(outputCfg.LogFile) && !isJournaldAvailable(outputCfg.LogFile) {
+				out = os.Stderr
+				break
+			}
+			// Keep using the current file or syslog/journald connection, if
+			// any, re-opening the former would force a rotation:
+			for _, currentOutput := range currentOutputs {
+				if currentOutput.logFile != nil && !reusedLogFiles[currentOutput.logFile] &&
+					sameLogFileConfig(currentOutput.cfg, outputCfg) {
+					output.logFile = currentOutput.logFile
+					break
+				}
+			}
+			if output.logFile == nil {
+				switch {
+				case isSyslogDestination(outputCfg.LogFile):
+					output.logFile, err = newSyslogWriter(outputCfg.LogFile)
+				case isJournaldDestination(outputCfg.LogFile):
+					output.logFile, err = newJournaldWriter(outputCfg.LogFile)
+				default:
+					output.logFile, err = newLogFile(outputCfg)
+				}
+				if err != nil {
+					for _, logFile := range newLogFiles {
+						logFile.Close()
+					}
+					return nil, nil, nil, wrapErr(i, err)
+				}
+				newLogFiles = append(newLogFiles, output.logFile)
+			}
+			reusedLogFiles[output.logFile] = true
+			out = output.logFile
+		}
+
+		if formatter, ok := output.formatter.(LoggerOutputAwareFormatter); ok {
+			if out != nil {
+				formatter.SetOutput(out)
+			} else {
+				formatter.SetOutput(logger.out.getOutput())
+			}
+		}
+
+		if i == 0 {
+			// The primary output writer is logrus.Logger.Out for all the
+			// loggers; it is never replaced, only its underlying output is,
+			// at apply time:
+			output.out = logger.out
+			primaryOut = out
+		} else {
+			output.out = &syncWriter{out: out}
+		}
+	}
+
+	if cfg.Async {
+		if err = logger.newAsyncWriters(cfg, outputs); err != nil {
+			for _, logFile := range newLogFiles {
+				logFile.Close()
+			}
+			return nil, nil, nil, err
+		}
+	}
+
+	unusedLogFiles = make([]io.Closer, 0)
+	for _, currentOutput := range currentOutputs {
+		if currentOutput.logFile != nil && !reusedLogFiles[currentOutput.logFile] {
+			unusedLogFiles = append(unusedLogFiles, currentOutput.logFile)
+		}
+	}
+	return outputs, primaryOut, unusedLogFiles, nil
+}
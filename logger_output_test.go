@@ -0,0 +1,106 @@
+package logrusx_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/bgp59/logrusx"
+)
+
+func TestLoggerOutputs(t *testing.T) {
+	logFile := path.Join(t.TempDir(), "app.log")
+	cfgYAML := `
+level: debug
+outputs:
+  - level: info
+    use_json: false
+    log_file: stderr
+  - log_file: ` + logFile + `
+`
+	cfg, err := logrusx.LoadLoggerConfigYAML([]byte(cfgYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The outputs should start from the defaults:
+	if got := cfg.Outputs[1]; !got.UseJson || got.LogFileMaxSizeMB != logrusx.LOGGER_CONFIG_LOG_FILE_MAX_SIZE_MB_DEFAULT {
+		t.Fatalf("outputs[1]: unexpected %#v", got)
+	}
+
+	logger := logrusx.NewCollectableLogger()
+	if err := logger.SetLogger(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer logger.SetLogger(&logrusx.LoggerConfig{LogFile: "stderr"})
+	primaryBuf := &bytes.Buffer{}
+	logger.SetOutput(primaryBuf)
+
+	compLogger := logger.NewCompLogger("comp")
+	logger.Debug("root debug")
+	logger.Info("root info")
+	compLogger.Debug("comp debug")
+	compLogger.Warn("comp warn")
+
+	primaryLines := strings.Split(strings.TrimSpace(primaryBuf.String()), "\n")
+	wantPrimary := []string{"root info", "comp warn"}
+	if len(primaryLines) != len(wantPrimary) {
+		t.Fatalf("primary: want %d lines, got %q", len(wantPrimary), primaryLines)
+	}
+	for i, want := range wantPrimary {
+		if !strings.Contains(primaryLines[i], want) || strings.HasPrefix(primaryLines[i], "{") {
+			t.Errorf("primary line# %d: want text w/ %q, got %q", i+1, want, primaryLines[i])
+		}
+	}
+
+	buf, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileLines := strings.Split(strings.TrimSpace(string(buf)), "\n")
+	wantFile := []string{"root debug", "root info", "comp debug", "comp warn"}
+	if len(fileLines) != len(wantFile) {
+		t.Fatalf("%s: want %d lines, got %q", logFile, len(wantFile), fileLines)
+	}
+	for i, want := range wantFile {
+		record := make(map[string]any)
+		if err := json.Unmarshal([]byte(fileLines[i]), &record); err != nil {
+			t.Fatalf("%s line# %d: %v", logFile, i+1, err)
+		}
+		if record["msg"] != want {
+			t.Errorf("%s line# %d: msg: want %q, got %q", logFile, i+1, want, record["msg"])
+		}
+	}
+}
+
+func TestLoggerOutputsConfigErrors(t *testing.T) {
+	for _, tc := range []struct {
+		yaml        string
+		wantErrSubs []string
+	}{
+		{
+			yaml:        "outputs:\n  - log_file: stderr\n    no_such_key: 1\n",
+			wantErrSubs: []string{"line 3", "no_such_key"},
+		},
+		{
+			yaml:        "outputs:\n  - log_file: stderr\n  - log_file: stdout\n    level: loud\n",
+			wantErrSubs: []string{"line 4", "outputs.1.level", "loud"},
+		},
+		{
+			yaml:        "outputs:\n  - log_file: stderr\n    format: xml\n",
+			wantErrSubs: []string{"line 3", "outputs.0.format", "logfmt"},
+		},
+	} {
+		_, err := logrusx.LoadLoggerConfigYAML([]byte(tc.yaml))
+		if err == nil {
+			t.Fatalf("%q: want error, got nil", tc.yaml)
+		}
+		for _, sub := range tc.wantErrSubs {
+			if !strings.Contains(err.Error(), sub) {
+				t.Errorf("error %q: missing %q", err, sub)
+			}
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Hot-reload of the logger config on file change and/or SIGHUP
+
+package logrusx
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	LOGGER_CONFIG_RELOADER_COMP_NAME = "logrusx"
+)
+
+// Reload a logger config from a YAML file and apply it to a live logger. The
+// reload may be triggered by a change of the file, detected via polling, by
+// SIGHUP or by an explicit Reload call. If the new config fails to load or to
+// apply, the previous one stays in effect.
+type LoggerConfigReloader struct {
+	logger  *CollectableLogger
+	cfgFile string
+	// How often to check the file for changes, use 0 to disable:
+	pollInterval time.Duration
+	// Whether to reload on SIGHUP, where supported:
+	reloadOnSighup bool
+	// The file state at the most recent check:
+	lastModTime time.Time
+	lastSize    int64
+	// Serialize reloads:
+	reloadMu sync.Mutex
+	// Start/Stop control:
+	m       sync.Mutex
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+	sigCh   chan os.Signal
+	running bool
+}
+
+func NewLoggerConfigReloader(
+	logger *CollectableLogger,
+	cfgFile string,
+	pollInterval time.Duration,
+	reloadOnSighup bool,
+) *LoggerConfigReloader {
+	return &LoggerConfigReloader{
+		logger:         logger,
+		cfgFile:        cfgFile,
+		pollInterval:   pollInterval,
+		reloadOnSighup: reloadOnSighup,
+	}
+}
+
+// Load the config file and apply it to the logger. A record describing the
+// changes, if any, is logged.
+func (r *LoggerConfigReloader) Reload() error {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	compLogger := r.logger.NewCompLogger(LOGGER_CONFIG_RELOADER_COMP_NAME)
+
+	if fi, err := os.Stat(r.cfgFile); err == nil {
+		r.lastModTime, r.lastSize = fi.ModTime(), fi.Size()
+	}
+
+	cfg, err := LoadLoggerConfigFile(r.cfgFile)
+	if err != nil {
+		compLogger.Errorf("logger config reload: %v, keep the previous config", err)
+		return err
+	}
+	prevCfg := r.logger.GetLoggerConfig()
+	if prevCfg == nil {
+		prevCfg = DefaultLoggerConfig()
+	}
+	err = r.logger.SetLogger(cfg)
+	if err != nil {
+		compLogger.Errorf("logger config reload: file: %q: %v, keep the previous config", r.cfgFile, err)
+		return err
+	}
+	changes := DiffLoggerConfig(prevCfg, cfg)
+	if len(changes) > 0 {
+		compLogger.Infof("logger config reloaded from %q: %s", r.cfgFile, strings.Join(changes, ", "))
+	} else {
+		compLogger.Debugf("logger config reloaded from %q: no changes", r.cfgFile)
+	}
+	return nil
+}
+
+// Start watching the file and/or listening for SIGHUP. The current state of
+// the file is used as reference for change detection, i.e. the config is
+// presumed to have been already applied.
+func (r *LoggerConfigReloader) Start() {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.running {
+		return
+	}
+
+	if fi, err := os.Stat(r.cfgFile); err == nil {
+		r.lastModTime, r.lastSize = fi.ModTime(), fi.Size()
+	}
+
+	r.stopCh = make(chan struct{})
+	r.doneCh = make(chan struct{})
+	if r.reloadOnSighup && len(loggerConfigReloadSignals) > 0 {
+		r.sigCh = make(chan os.Signal, 1)
+		signal.Notify(r.sigCh, loggerConfigReloadSignals...)
+	} else {
+		r.sigCh = nil
+	}
+	r.running = true
+	go r.loop()
+}
+
+// Stop watching and wait for the watcher to complete.
+func (r *LoggerConfigReloader) Stop() {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if !r.running {
+		return
+	}
+	if r.sigCh != nil {
+		signal.Stop(r.sigCh)
+	}
+	close(r.stopCh)
+	<-r.doneCh
+	r.running = false
+}
+
+func (r *LoggerConfigReloader) loop() {
+	defer close(r.doneCh)
+
+	var pollCh <-chan time.Time
+	if r.pollInterval > 0 {
+		ticker := time.NewTicker(r.pollInterval)
+		defer ticker.Stop()
+		pollCh = ticker.C
+	}
+
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case <-r.sigCh:
+			r.Reload()
+		case <-pollCh:
+			if r.fileChanged() {
+				r.Reload()
+			}
+		}
+	}
+}
+
+func (r *LoggerConfigReloader) fileChanged() bool {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+	fi, err := os.Stat(r.cfgFile)
+	if err != nil {
+		// Missing file, most likely in the middle of being replaced, wait
+		// for the next check:
+		return false
+	}
+	return !fi.ModTime().Equal(r.lastModTime) || fi.Size() != r.lastSize
+}
+
+// Return the list of differences between 2 configs, as "key: old -> new", in
+// field order. The keys are based on the yaml tags.
+func DiffLoggerConfig(oldCfg, newCfg *LoggerConfig) []string {
+	changes := make([]string, 0)
+	oldVal, newVal := reflect.ValueOf(oldCfg).Elem(), reflect.ValueOf(newCfg).Elem()
+	cfgType := oldVal.Type()
+	for i := 0; i < cfgType.NumField(); i++ {
+		field := cfgType.Field(i)
+		oldField, newField := oldVal.Field(i).Interface(), newVal.Field(i).Interface()
+		if reflect.DeepEqual(oldField, newField) {
+			continue
+		}
+		key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if key == "" {
+			key = field.Name
+		}
+		changes = append(changes, fmt.Sprintf("%s: %#v -> %#v", key, oldField, newField))
+	}
+	return changes
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package logrusx
+
+import (
+	"os"
+)
+
+// No signal support, the config can be reloaded only upon file change:
+var loggerConfigReloadSignals = []os.Signal{}
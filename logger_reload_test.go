@@ -0,0 +1,102 @@
+package logrusx_test
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/bgp59/logrusx"
+	"github.com/sirupsen/logrus"
+
+	logrusx_testutils "github.com/bgp59/logrusx/testutils"
+)
+
+func writeTestCfgFile(t *testing.T, cfgFile string, content string) {
+	err := os.WriteFile(cfgFile, []byte(content), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoggerConfigReload(t *testing.T) {
+	cfgFile := path.Join(t.TempDir(), "logger.yaml")
+	writeTestCfgFile(t, cfgFile, "use_json: false\nlevel: info\n")
+
+	logger := logrusx.NewCollectableLogger()
+	tlc := logrusx_testutils.NewTestCollectableLogger(t, logger, nil)
+	defer tlc.RestoreLog()
+
+	reloader := logrusx.NewLoggerConfigReloader(logger, cfgFile, 0, false)
+	if err := reloader.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := logger.GetLevel(); got != logrus.InfoLevel {
+		t.Fatalf("level: want %v, got %v", logrus.InfoLevel, got)
+	}
+
+	// Invalid config, the previous one should stay in effect:
+	writeTestCfgFile(t, cfgFile, "use_json: false\nlevel: chatty\n")
+	if err := reloader.Reload(); err == nil {
+		t.Fatal("want error, got nil")
+	}
+	if got := logger.GetLevel(); got != logrus.InfoLevel {
+		t.Fatalf("level: want %v, got %v", logrus.InfoLevel, got)
+	}
+
+	writeTestCfgFile(t, cfgFile, "use_json: false\nlevel: debug\n")
+	if err := reloader.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := logger.GetLevel(); got != logrus.DebugLevel {
+		t.Fatalf("level: want %v, got %v", logrus.DebugLevel, got)
+	}
+	if !logger.IsEnabledForDebug {
+		t.Fatal("IsEnabledForDebug: want true, got false")
+	}
+}
+
+func TestLoggerConfigReloadPoll(t *testing.T) {
+	cfgFile := path.Join(t.TempDir(), "logger.yaml")
+	writeTestCfgFile(t, cfgFile, "use_json: false\nlevel: info\n")
+
+	logger := logrusx.NewCollectableLogger()
+	tlc := logrusx_testutils.NewTestCollectableLogger(t, logger, nil)
+	defer tlc.RestoreLog()
+
+	reloader := logrusx.NewLoggerConfigReloader(logger, cfgFile, 10*time.Millisecond, false)
+	if err := reloader.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	reloader.Start()
+	defer reloader.Stop()
+
+	writeTestCfgFile(t, cfgFile, "use_json: false\nlevel: warning\n")
+	deadline := time.Now().Add(2 * time.Second)
+	for logger.GetLevel() != logrus.WarnLevel {
+		if time.Now().After(deadline) {
+			t.Fatalf("level: want %v, got %v", logrus.WarnLevel, logger.GetLevel())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDiffLoggerConfig(t *testing.T) {
+	oldCfg := logrusx.DefaultLoggerConfig()
+	newCfg := logrusx.DefaultLoggerConfig()
+	newCfg.Level = "debug"
+	newCfg.LogFileMaxBackupNum = 3
+	want := []string{
+		`level: "info" -> "debug"`,
+		`log_file_max_backup_num: 1 -> 3`,
+	}
+	got := logrusx.DiffLoggerConfig(oldCfg, newCfg)
+	if len(got) != len(want) {
+		t.Fatalf("changes: want %q, got %q", want, got)
+	}
+	for i := range want {
+		if want[i] != got[i] {
+			t.Errorf("changes[%d]: want %q, got %q", i, want[i], got[i])
+		}
+	}
+}
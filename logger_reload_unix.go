@@ -0,0 +1,11 @@
+//go:build unix
+
+package logrusx
+
+import (
+	"os"
+	"syscall"
+)
+
+// The signals for reloading the config, if enabled:
+var loggerConfigReloadSignals = []os.Signal{syscall.SIGHUP}
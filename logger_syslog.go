@@ -0,0 +1,177 @@
+// Syslog output support
+
+package logrusx
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	logrusx_internal "github.com/bgp59/logrusx/internal"
+)
+
+// Syslog destinations are URLs, used in lieu of the log file:
+//
+//	syslog://[/path]             local syslog, the default path is the 1st of
+//	                             /dev/log, /var/run/syslog, /var/run/log found
+//	syslog+udp://host[:port]     remote syslog over UDP, default port 514
+//	syslog+tcp://host[:port]     remote syslog over TCP, default port 514
+//	syslog+unix:///path          unix stream socket
+//	syslog+unixgram:///path      unix datagram socket
+//	unix:///path                 same as syslog+unix
+//	unixgram:///path             same as syslog+unixgram
+//
+// The following query parameters are supported:
+//
+//	format=rfc5424|rfc3164       default rfc3164 for unix sockets and rfc5424
+//	                             for UDP and TCP
+//	facility=NAME                default user
+//	app_name=NAME                default the program name
+//	hostname=NAME                default the local hostname for UDP and TCP,
+//	                             none for unix sockets
+//
+// e.g. syslog+udp://loghost?facility=local0&app_name=myapp
+const (
+	LOGGER_SYSLOG_DEFAULT_PORT     = "514"
+	LOGGER_SYSLOG_FACILITY_DEFAULT = "user"
+)
+
+var syslogSchemeNetwork = map[string]string{
+	"syslog":          "",
+	"syslog+udp":      "udp",
+	"syslog+tcp":      "tcp",
+	"syslog+unix":     "unix",
+	"syslog+unixgram": "unixgram",
+	"unix":            "unix",
+	"unixgram":        "unixgram",
+}
+
+type syslogDestination struct {
+	network  string
+	address  string
+	rfc      string
+	facility int
+	appName  string
+	hostname string
+}
+
+// Whether the log file is in fact a syslog destination:
+func isSyslogDestination(logFile string) bool {
+	scheme, _, ok := strings.Cut(logFile, "://")
+	if !ok {
+		return false
+	}
+	_, ok = syslogSchemeNetwork[scheme]
+	return ok
+}
+
+func parseSyslogDestination(dest string) (*syslogDestination, error) {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return nil, err
+	}
+	network, ok := syslogSchemeNetwork[u.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("%q: invalid syslog scheme %q", dest, u.Scheme)
+	}
+
+	sd := &syslogDestination{network: network}
+	remote := false
+	switch network {
+	case "udp", "tcp":
+		if u.Hostname() == "" {
+			return nil, fmt.Errorf("%q: missing host", dest)
+		}
+		port := u.Port()
+		if port == "" {
+			port = LOGGER_SYSLOG_DEFAULT_PORT
+		}
+		sd.address = net.JoinHostPort(u.Hostname(), port)
+		remote = true
+	default:
+		if u.Host != "" {
+			return nil, fmt.Errorf("%q: unexpected host %q for unix socket", dest, u.Host)
+		}
+		if network != "" && u.Path == "" {
+			return nil, fmt.Errorf("%q: missing socket path", dest)
+		}
+		sd.address = u.Path
+	}
+
+	query := u.Query()
+	for key := range query {
+		switch key {
+		case "format", "facility", "app_name", "hostname":
+		default:
+			return nil, fmt.Errorf("%q: invalid query parameter %q", dest, key)
+		}
+	}
+
+	sd.rfc = query.Get("format")
+	switch sd.rfc {
+	case "":
+		if remote {
+			sd.rfc = logrusx_internal.SYSLOG_RFC5424
+		} else {
+			sd.rfc = logrusx_internal.SYSLOG_RFC3164
+		}
+	case logrusx_internal.SYSLOG_RFC5424, logrusx_internal.SYSLOG_RFC3164:
+	default:
+		return nil, fmt.Errorf(
+			"%q: invalid format %q, must be one of %v",
+			dest, sd.rfc, []string{logrusx_internal.SYSLOG_RFC5424, logrusx_internal.SYSLOG_RFC3164},
+		)
+	}
+
+	facility := query.Get("facility")
+	if facility == "" {
+		facility = LOGGER_SYSLOG_FACILITY_DEFAULT
+	}
+	if sd.facility, ok = logrusx_internal.SyslogFacilityByName[facility]; !ok {
+		return nil, fmt.Errorf(
+			"%q: invalid facility %q, must be one of %v",
+			dest, facility, logrusx_internal.GetSyslogFacilityNames(),
+		)
+	}
+
+	sd.appName = query.Get("app_name")
+	if sd.appName == "" {
+		sd.appName = path.Base(os.Args[0])
+	}
+
+	sd.hostname = query.Get("hostname")
+	if sd.hostname == "" && remote {
+		sd.hostname, _ = os.Hostname()
+	}
+
+	return sd, nil
+}
+
+func newSyslogWriter(dest string) (*logrusx_internal.SyslogWriter, error) {
+	sd, err := parseSyslogDestination(dest)
+	if err != nil {
+		return nil, err
+	}
+	writer := &logrusx_internal.SyslogWriter{
+		Network: sd.network,
+		Address: sd.address,
+	}
+	// Connect now to report errors early:
+	if err := writer.Connect(); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (logger *CollectableLogger) newSyslogFormatter(dest string, cfg *LoggerOutputConfig) (*logrusx_internal.SyslogFormatter, error) {
+	sd, err := parseSyslogDestination(dest)
+	if err != nil {
+		return nil, err
+	}
+	return logrusx_internal.NewSyslogFormatter(
+		sd.rfc, sd.facility, sd.hostname, sd.appName, logger.prettyfier, newFieldKeyOrder(cfg),
+	), nil
+}
@@ -0,0 +1,244 @@
+package logrusx_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bgp59/logrusx"
+)
+
+// Receive the messages from a datagram listener:
+func readSyslogPackets(t *testing.T, conn net.PacketConn, n int) []string {
+	msgs := make([]string, 0, n)
+	buf := make([]byte, 65536)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for len(msgs) < n {
+		k, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, string(buf[:k]))
+	}
+	return msgs
+}
+
+// Receive the octet counting framed messages from a stream listener; it runs
+// in its own goroutine, so it cannot fail the test:
+func readSyslogStream(listener net.Listener, n int) ([]string, error) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	msgs := make([]string, 0, n)
+	for len(msgs) < n {
+		lenStr, err := r.ReadString(' ')
+		if err != nil {
+			return nil, err
+		}
+		msgLen, err := strconv.Atoi(strings.TrimSpace(lenStr))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, msgLen)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, string(buf))
+	}
+	return msgs, nil
+}
+
+func logSyslogRecords(t *testing.T, dest string) {
+	logger := logrusx.NewCollectableLogger()
+	cfg := logrusx.DefaultLoggerConfig()
+	cfg.LogFile = dest
+	if err := logger.SetLogger(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer logger.SetLogger(&logrusx.LoggerConfig{LogFile: "stderr"})
+	logger.WithField("key", `a "quoted"] value`).Info("info record")
+	logger.NewCompLogger("comp").Warn("warn record")
+}
+
+func TestSyslogRFC5424UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	logSyslogRecords(t, fmt.Sprintf("syslog+udp://%s?facility=local0&app_name=test-app", conn.LocalAddr()))
+	msgs := readSyslogPackets(t, conn, 2)
+
+	pid := os.Getpid()
+	for i, wantRe := range []string{
+		fmt.Sprintf(
+			`^<134>1 \S+ \S+ test-app %d - \[logrus@32473 file="\S+" key="a \\"quoted\\"\\] value"\] info record$`,
+			pid,
+		),
+		fmt.Sprintf(`^<132>1 \S+ \S+ test-app %d - \[logrus@32473 comp="comp" file="\S+"\] warn record$`, pid),
+	} {
+		if !regexp.MustCompile(wantRe).MatchString(msgs[i]) {
+			t.Errorf("msg# %d: want match %q, got %q", i+1, wantRe, msgs[i])
+		}
+	}
+}
+
+func TestSyslogKeptOutput(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	logger := logrusx.NewCollectableLogger()
+	cfg := logrusx.DefaultLoggerConfig()
+	cfg.LogFile = fmt.Sprintf("syslog+udp://%s?app_name=test-app", conn.LocalAddr())
+	if err := logger.SetLogger(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer logger.SetLogger(&logrusx.LoggerConfig{LogFile: "stderr"})
+	// An empty log file keeps the current output, w/ its format:
+	cfg.LogFile = ""
+	if err := logger.SetLogger(cfg); err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("info record")
+	msgs := readSyslogPackets(t, conn, 1)
+	wantRe := `^<14>1 \S+ \S+ test-app \d+ - \[logrus@32473 file="\S+"\] info record$`
+	if !regexp.MustCompile(wantRe).MatchString(msgs[0]) {
+		t.Errorf("want match %q, got %q", wantRe, msgs[0])
+	}
+}
+
+func TestSyslogFuncName(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	logger := logrusx.NewCollectableLogger()
+	cfg := logrusx.DefaultLoggerConfig()
+	cfg.LogFile = fmt.Sprintf("syslog+udp://%s?app_name=test-app", conn.LocalAddr())
+	cfg.FuncName = logrusx.LOGGER_FUNC_NAME_SHORT
+	if err := logger.SetLogger(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer logger.SetLogger(&logrusx.LoggerConfig{LogFile: "stderr"})
+	logger.Info("info record")
+	msgs := readSyslogPackets(t, conn, 1)
+	wantRe := `^<14>1 \S+ \S+ test-app \d+ - \[logrus@32473 file="\S+" func="logrusx_test\.TestSyslogFuncName"\] info record$`
+	if !regexp.MustCompile(wantRe).MatchString(msgs[0]) {
+		t.Errorf("want match %q, got %q", wantRe, msgs[0])
+	}
+}
+
+func TestSyslogFieldOrder(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	logger := logrusx.NewCollectableLogger()
+	cfg := logrusx.DefaultLoggerConfig()
+	cfg.LogFile = fmt.Sprintf("syslog+udp://%s?app_name=test-app", conn.LocalAddr())
+	cfg.FieldOrderLeading = []string{"z", "comp"}
+	cfg.FieldOrderTrailing = []string{"file"}
+	if err := logger.SetLogger(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer logger.SetLogger(&logrusx.LoggerConfig{LogFile: "stderr"})
+	logger.NewCompLogger("comp").WithField("a", 1).WithField("z", 2).Info("info record")
+	msgs := readSyslogPackets(t, conn, 1)
+	wantRe := `^<14>1 \S+ \S+ test-app \d+ - \[logrus@32473 z="2" comp="comp" a="1" file="\S+"\] info record$`
+	if !regexp.MustCompile(wantRe).MatchString(msgs[0]) {
+		t.Errorf("want match %q, got %q", wantRe, msgs[0])
+	}
+}
+
+func TestSyslogRFC3164Unixgram(t *testing.T) {
+	sockPath := path.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenPacket("unixgram", sockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	logSyslogRecords(t, "unixgram://"+sockPath+"?app_name=test-app")
+	msgs := readSyslogPackets(t, conn, 2)
+
+	pid := os.Getpid()
+	for i, wantRe := range []string{
+		fmt.Sprintf(`^<14>\w{3} [ \d]\d \d\d:\d\d:\d\d test-app\[%d\]: info record file=\S+ key="a \\"quoted\\"] value"$`, pid),
+		fmt.Sprintf(`^<12>\w{3} [ \d]\d \d\d:\d\d:\d\d test-app\[%d\]: warn record comp=comp file=\S+$`, pid),
+	} {
+		if !regexp.MustCompile(wantRe).MatchString(msgs[i]) {
+			t.Errorf("msg# %d: want match %q, got %q", i+1, wantRe, msgs[i])
+		}
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	var msgs []string
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		msgs, err = readSyslogStream(listener, 2)
+		done <- err
+	}()
+	logSyslogRecords(t, fmt.Sprintf("syslog+tcp://%s?hostname=test-host", listener.Addr()))
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []string{"info record", "warn record"} {
+		if !strings.Contains(msgs[i], " test-host ") || !strings.HasSuffix(msgs[i], want) {
+			t.Errorf("msg# %d: want test-host and %q, got %q", i+1, want, msgs[i])
+		}
+	}
+}
+
+func TestSyslogConfigErrors(t *testing.T) {
+	for _, tc := range []struct {
+		logFile     string
+		wantErrSubs []string
+	}{
+		{"syslog+udp://", []string{"missing host"}},
+		{"syslog+unixgram://", []string{"missing socket path"}},
+		{"syslog+udp://loghost?facility=nosuch", []string{"invalid facility", "local0"}},
+		{"syslog+udp://loghost?format=rfc1", []string{"invalid format"}},
+		{"syslog+udp://loghost?nosuch=1", []string{"invalid query parameter"}},
+	} {
+		cfg := logrusx.DefaultLoggerConfig()
+		cfg.LogFile = tc.logFile
+		err := cfg.Validate()
+		if err == nil {
+			t.Fatalf("%q: want error, got nil", tc.logFile)
+		}
+		for _, sub := range append(tc.wantErrSubs, "log_file") {
+			if !strings.Contains(err.Error(), sub) {
+				t.Errorf("error %q: missing %q", err, sub)
+			}
+		}
+	}
+}
@@ -1,9 +1,15 @@
 package logrusx_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/bgp59/logrusx"
+	"github.com/sirupsen/logrus"
 
 	logrusx_testutils "github.com/bgp59/logrusx/testutils"
 )
@@ -49,3 +55,173 @@ func TestLogConfig(t *testing.T) {
 		t.Run("", func(t *testing.T) { testLogConfig(t, cfg) })
 	}
 }
+
+func TestLoggerSrcFileModuleRoot(t *testing.T) {
+	for _, tc := range []struct {
+		srcFileModulePath bool
+		wantFile          string
+	}{
+		{false, `file="logger_test.go:`},
+		{true, `file="github.com/bgp59/logrusx/logger_test.go:`},
+	} {
+		logger := logrusx.NewCollectableLogger()
+		cfg := logrusx.DefaultLoggerConfig()
+		cfg.Format = logrusx.LOGGER_FORMAT_TEXT
+		cfg.SrcFileModulePath = tc.srcFileModulePath
+		if err := logger.SetLogger(cfg); err != nil {
+			t.Fatal(err)
+		}
+		buf := &bytes.Buffer{}
+		logger.SetOutput(buf)
+		logger.Info("record")
+		if got := buf.String(); !strings.Contains(got, tc.wantFile) {
+			t.Errorf("src_file_module_path=%v: want %q, got %q", tc.srcFileModulePath, tc.wantFile, got)
+		}
+	}
+}
+
+func TestLoggerAddModulePathPrefix(t *testing.T) {
+	var callerPrettyfier logrusx.LoggerCallerPrettyfier
+	logrusx.RegisterLoggerFormat("test-prettyfier", func(opts *logrusx.LoggerFormatterOptions) logrus.Formatter {
+		callerPrettyfier = opts.CallerPrettyfier
+		return &logrus.TextFormatter{}
+	})
+	t.Cleanup(func() { logrusx.UnregisterLoggerFormat("test-prettyfier") })
+
+	// As reported for -trimpath builds:
+	frame := &runtime.Frame{
+		PC:       1,
+		File:     "example.com/org/mod@v1.2.3/pkg/file.go",
+		Line:     7,
+		Function: "example.com/org/mod/pkg.Func",
+	}
+	logger := logrusx.NewCollectableLogger()
+	cfg := logrusx.DefaultLoggerConfig()
+	cfg.Format = "test-prettyfier"
+	cfg.SrcFileModulePath = true
+	for _, tc := range []struct {
+		addModulePath        bool
+		srcFileModuleVersion bool
+		want                 string
+	}{
+		// The fallback, keep 1 dir:
+		{false, false, "pkg/file.go:7"},
+		{true, false, "example.com/org/mod/pkg/file.go:7"},
+		{true, true, "example.com/org/mod@v1.2.3/pkg/file.go:7"},
+	} {
+		if tc.addModulePath {
+			logger.AddModulePathPrefix("example.com/org/mod")
+		}
+		cfg.SrcFileModuleVersion = tc.srcFileModuleVersion
+		if err := logger.SetLogger(cfg); err != nil {
+			t.Fatal(err)
+		}
+		if _, got := callerPrettyfier(frame); got != tc.want {
+			t.Errorf("addModulePath=%v, srcFileModuleVersion=%v: want %q, got %q", tc.addModulePath, tc.srcFileModuleVersion, tc.want, got)
+		}
+	}
+}
+
+func testLoggerFuncNameHelper(logger *logrusx.CollectableLogger) {
+	logger.Info("record")
+}
+
+func TestLoggerFuncName(t *testing.T) {
+	for _, tc := range []struct {
+		funcName string
+		wantFunc string
+	}{
+		{logrusx.LOGGER_FUNC_NAME_OFF, ""},
+		{logrusx.LOGGER_FUNC_NAME_SHORT, "logrusx_test.testLoggerFuncNameHelper"},
+		{logrusx.LOGGER_FUNC_NAME_METHOD, "testLoggerFuncNameHelper"},
+		{logrusx.LOGGER_FUNC_NAME_FULL, "github.com/bgp59/logrusx_test.testLoggerFuncNameHelper"},
+	} {
+		logger := logrusx.NewCollectableLogger()
+		cfg := logrusx.DefaultLoggerConfig()
+		cfg.Format = logrusx.LOGGER_FORMAT_JSON
+		cfg.FuncName = tc.funcName
+		if err := logger.SetLogger(cfg); err != nil {
+			t.Fatal(err)
+		}
+		buf := &bytes.Buffer{}
+		logger.SetOutput(buf)
+		testLoggerFuncNameHelper(logger)
+		record := make(map[string]any)
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		gotFunc, _ := record["func"].(string)
+		if gotFunc != tc.wantFunc {
+			t.Errorf("func_name=%q: want %q, got %q", tc.funcName, tc.wantFunc, gotFunc)
+		}
+	}
+
+	cfg := logrusx.DefaultLoggerConfig()
+	cfg.FuncName = "long"
+	if err := logrusx.NewCollectableLogger().SetLogger(cfg); err == nil || !strings.Contains(err.Error(), "func_name") {
+		t.Errorf("want func_name error, got %v", err)
+	}
+}
+
+// Run w/ -cpu, e.g. -cpu 1,4,16, for GOMAXPROCS-wide parallel logging:
+func BenchmarkLoggerParallel(b *testing.B) {
+	logger := logrusx.NewCollectableLogger()
+	if err := logger.SetLogger(logrusx.DefaultLoggerConfig()); err != nil {
+		b.Fatal(err)
+	}
+	logger.SetOutput(io.Discard)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			logger.Info("record")
+		}
+	})
+}
+
+func TestGetLoggerConfigCopy(t *testing.T) {
+	logger := logrusx.NewCollectableLogger()
+	cfg := logrusx.DefaultLoggerConfig()
+	cfg.Format = logrusx.LOGGER_FORMAT_JSON
+	cfg.JsonFieldMap = map[string]string{"msg": "message"}
+	cfg.FieldOrderLeading = []string{"time"}
+	cfg.FieldOrderTrailing = nil
+	outputCfg := logrusx.DefaultLoggerOutputConfig()
+	outputCfg.Format = logrusx.LOGGER_FORMAT_JSON
+	outputCfg.JsonFieldMap = map[string]string{"msg": "message"}
+	outputCfg.FieldOrderLeading = []string{"time"}
+	outputCfg.FieldOrderTrailing = []string{}
+	cfg.Outputs = []logrusx.LoggerOutputConfig{*outputCfg}
+	if err := logger.SetLogger(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.JsonFieldMap["msg"] = "changed"
+	cfg.Outputs[0].JsonFieldMap["msg"] = "changed"
+	cfg.FieldOrderLeading[0] = "changed"
+	cfg.Outputs[0].FieldOrderLeading[0] = "changed"
+
+	gotCfg := logger.GetLoggerConfig()
+	if got := gotCfg.JsonFieldMap["msg"]; got != "message" {
+		t.Errorf("JsonFieldMap: want %q, got %q", "message", got)
+	}
+	if got := gotCfg.Outputs[0].JsonFieldMap["msg"]; got != "message" {
+		t.Errorf("Outputs[0].JsonFieldMap: want %q, got %q", "message", got)
+	}
+	if got := gotCfg.FieldOrderLeading[0]; got != "time" {
+		t.Errorf("FieldOrderLeading: want %q, got %q", "time", got)
+	}
+	if got := gotCfg.Outputs[0].FieldOrderLeading[0]; got != "time" {
+		t.Errorf("Outputs[0].FieldOrderLeading: want %q, got %q", "time", got)
+	}
+	// nil stands for the default order, empty for none:
+	if gotCfg.FieldOrderTrailing != nil {
+		t.Errorf("FieldOrderTrailing: want nil, got %q", gotCfg.FieldOrderTrailing)
+	}
+	if got := gotCfg.Outputs[0].FieldOrderTrailing; got == nil || len(got) != 0 {
+		t.Errorf("Outputs[0].FieldOrderTrailing: want empty, got %#v", got)
+	}
+	gotCfg.JsonFieldMap["msg"] = "changed"
+	if got := logger.GetLoggerConfig().JsonFieldMap["msg"]; got != "message" {
+		t.Errorf("JsonFieldMap after changing the copy: want %q, got %q", "message", got)
+	}
+}